@@ -14,6 +14,7 @@ replace github.com/spezifisch/tview-command => ../../
 
 require (
 	github.com/BurntSushi/toml v1.4.0 // indirect
+	github.com/expr-lang/expr v1.16.9 // indirect
 	github.com/gdamore/encoding v1.0.0 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/mattn/go-runewidth v0.0.15 // indirect
@@ -21,4 +22,5 @@ require (
 	golang.org/x/sys v0.17.0 // indirect
 	golang.org/x/term v0.17.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
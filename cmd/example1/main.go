@@ -10,6 +10,7 @@ import (
 	"github.com/rivo/tview"
 	"github.com/sirupsen/logrus"
 	tviewcommand "github.com/spezifisch/tview-command"
+	"github.com/spezifisch/tview-command/command"
 	"github.com/spezifisch/tview-command/keybinding"
 	tcLog "github.com/spezifisch/tview-command/log"
 )
@@ -27,10 +28,44 @@ func main() {
 
 	logger.Info("Starting tview-command example1...")
 
+	// Register the commands the keybindings can refer to. The handlers only
+	// report what they would do, except for quit.
+	var lastAction string
+	registry := command.NewRegistry()
+	for name, description := range map[string]string{
+		"closeModal":         "Close the modal",
+		"copy":               "Copy",
+		"paste":              "Paste",
+		"cut":                "Cut",
+		"undo":               "Undo",
+		"deleteTrack":        "Delete the selected track",
+		"addToQueue":         "Add the selected track to the queue",
+		"openCommandPalette": "Open the command palette",
+		"queue.moveTrack":    "Move the selected track",
+		"queue.shuffle":      "Shuffle the queue",
+	} {
+		registry.MustRegister(command.Command{
+			Name:        name,
+			Description: description,
+			Handler: func(call *command.Call) error {
+				lastAction = call.Name
+				return nil
+			},
+		})
+	}
+	registry.MustRegister(command.Command{
+		Name:        "quit",
+		Description: "Quit the application",
+		Handler: func(call *command.Call) error {
+			app.Stop()
+			return nil
+		},
+	})
+
 	// Load the config.toml file for keybindings
 	configPath := "config_example1.toml"
 	logger.Infof("Loading keybinding config from %s", configPath)
-	config, err := keybinding.LoadConfig(configPath)
+	config, err := keybinding.LoadConfig(configPath, keybinding.WithRegistry(registry))
 	if err != nil {
 		logger.Fatalf("Failed to load keybinding config: %v", err)
 	}
@@ -74,10 +109,15 @@ func main() {
 	}
 	fmt.Fprintf(queueScreen, "\nPRESS any key to start demo\n")
 
+	// The queue screen is the only widget, so its context stays on top of Global
+	contextStack := tviewcommand.NewContextStack()
+	contextStack.Push("Queue")
+
 	// Handle global key events for queueScreen and update config dump
 	counter := 0
 	queueScreen.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		counter++
+		lastAction = ""
 		logger.Debugf("Key event received: counter=%d", counter)
 
 		// Override on 'Q' to ensure quitting the application
@@ -88,20 +128,23 @@ func main() {
 			return nil
 		}
 
-		// Resolve the keybindings for the Queue context dynamically
-		contextKey := "Queue"
+		// Resolve the keybindings for the Queue context, falling through to Global
+		contextKey := contextStack.Current()
 		logger.Debugf("Using context: %s", contextKey)
 
 		// Handle key input using FromEventKey
 		tcEvent := tviewcommand.FromEventKey(event, config)
 
-		// Use the LookupCommand function to find the action
-		if err := tcEvent.LookupCommand(contextKey); err != nil {
+		// Use the LookupStack function to find the action on the context stack
+		if _, err := tcEvent.LookupStack(contextStack); err != nil {
 			fmt.Fprintf(queueScreen, "(internal tview-command error)")
 			logger.Errorf("Internal tview-command error for key: %s", tcEvent.KeyName)
 		} else if tcEvent.IsBound {
-			fmt.Fprintf(queueScreen, "%s", tview.Escape(tcEvent.Command))
-			logger.Infof("Triggered action for key '%s': %s", tcEvent.KeyName, tcEvent.Command)
+			// Run the command's handler
+			if err := registry.Dispatch(tcEvent); err != nil {
+				logger.Errorf("Command '%s' failed: %v", tcEvent.Command, err)
+			}
+			logger.Infof("Triggered action for key '%s' in context '%s': %s", tcEvent.KeyName, tcEvent.Context, tcEvent.Command)
 		} else {
 			fmt.Fprintf(queueScreen, "(shortcut not bound)")
 			logger.Warnf("No action bound for key: %s", tcEvent.KeyName)
@@ -116,7 +159,7 @@ Event received!
 Event counter: %d
 
 Event: %s
-Triggered action: `, contextKey, counter, tview.Escape(tcEvent.String()))
+Triggered action: %s`, contextKey, counter, tview.Escape(tcEvent.String()), tview.Escape(lastAction))
 
 		printHelp()
 
@@ -0,0 +1,354 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spezifisch/tview-command/types"
+)
+
+// ErrNotBound is returned by Dispatch for events without a command.
+var ErrNotBound = errors.New("event is not bound to a command")
+
+// ArgType is the type of a command argument.
+type ArgType int
+
+const (
+	ArgString ArgType = iota
+	ArgInt
+	ArgFloat
+	ArgBool
+)
+
+func (t ArgType) String() string {
+	switch t {
+	case ArgString:
+		return "string"
+	case ArgInt:
+		return "int"
+	case ArgFloat:
+		return "float"
+	case ArgBool:
+		return "bool"
+	}
+	return fmt.Sprintf("ArgType(%d)", int(t))
+}
+
+// ArgSpec describes one argument of a command.
+type ArgSpec struct {
+	Name     string
+	Type     ArgType
+	Optional bool
+}
+
+// Call is what a Handler gets when its command is dispatched.
+type Call struct {
+	Name  string
+	Args  []interface{} // converted to the types of the command's ArgSpecs
+	Event *types.Event
+}
+
+// Handler runs a command.
+type Handler func(call *Call) error
+
+// Command is a named handler that bindings can refer to, like
+// "queue.deleteTrack".
+type Command struct {
+	Name        string
+	Description string
+	Args        []ArgSpec
+	Handler     Handler
+}
+
+// EnvFunc returns the environment that the expression of a binding runs
+// against, e.g. a struct with the app's functions and current state.
+type EnvFunc func(e *types.Event) interface{}
+
+// Registry holds the commands an app provides.
+type Registry struct {
+	mu       sync.RWMutex
+	commands map[string]*Command
+	env      EnvFunc
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		commands: make(map[string]*Command),
+	}
+}
+
+// Register adds a command. Names must be unique and must not contain
+// whitespace. Optional arguments can only be followed by optional ones.
+func (r *Registry) Register(cmd Command) error {
+	if cmd.Name == "" || strings.ContainsAny(cmd.Name, " \t\n;") {
+		return fmt.Errorf("invalid command name '%s'", cmd.Name)
+	}
+	if cmd.Handler == nil {
+		return fmt.Errorf("command '%s' has no handler", cmd.Name)
+	}
+	for i := 1; i < len(cmd.Args); i++ {
+		if cmd.Args[i-1].Optional && !cmd.Args[i].Optional {
+			return fmt.Errorf("command '%s': required argument '%s' follows an optional one", cmd.Name, cmd.Args[i].Name)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.commands[cmd.Name]; exists {
+		return fmt.Errorf("command '%s' is already registered", cmd.Name)
+	}
+	r.commands[cmd.Name] = &cmd
+	return nil
+}
+
+// MustRegister is like Register but panics on error. It is meant for the
+// static command tables of an app.
+func (r *Registry) MustRegister(cmd Command) {
+	if err := r.Register(cmd); err != nil {
+		panic(err)
+	}
+}
+
+// SetExprEnv sets the function that provides the environment for expression
+// bindings. It should return a value of the type the config was loaded with,
+// see keybinding.WithExprEnv. Without it expressions run without environment.
+func (r *Registry) SetExprEnv(env EnvFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.env = env
+}
+
+// Lookup returns the command registered under name.
+func (r *Registry) Lookup(name string) (Command, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cmd, ok := r.commands[name]
+	if !ok {
+		return Command{}, false
+	}
+	return *cmd, true
+}
+
+// Commands returns all registered commands sorted by name, e.g. for a help
+// screen or a command palette.
+func (r *Registry) Commands() []Command {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	commands := make([]Command, 0, len(r.commands))
+	for _, cmd := range r.commands {
+		commands = append(commands, *cmd)
+	}
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i].Name < commands[j].Name
+	})
+	return commands
+}
+
+// Dispatch runs the commands an event was bound to by LookupCommand or
+// LookupStack. In a chain like "a && b; c", b only runs if a succeeded and c
+// runs in any case. Nothing runs if one of the commands is unknown or has
+// invalid arguments. The first error of a handler is returned. Unbound
+// events give ErrNotBound.
+//
+// Expression bindings are run against the environment set with SetExprEnv.
+// If the expression returns an error, that error is returned.
+func (r *Registry) Dispatch(e *types.Event) error {
+	if !e.IsBound {
+		return ErrNotBound
+	}
+	if e.Binding.IsExpr() {
+		return r.run(e)
+	}
+
+	commands := e.Commands
+	if commands == nil {
+		var err error
+		if commands, err = types.ParseCommands(e.Command); err != nil {
+			return err
+		}
+	}
+
+	calls, handlers, err := r.prepare(commands)
+	if err != nil {
+		return err
+	}
+
+	var firstErr, lastErr error
+	for i, call := range calls {
+		if i > 0 && commands[i-1].Next == types.OpAnd && lastErr != nil {
+			// skipped commands keep the status of the failed one
+			continue
+		}
+		call.Event = e
+		lastErr = handlers[i](call)
+		if lastErr != nil && firstErr == nil {
+			firstErr = lastErr
+		}
+	}
+	return firstErr
+}
+
+// run runs the expression binding of e.
+func (r *Registry) run(e *types.Event) error {
+	r.mu.RLock()
+	envFunc := r.env
+	r.mu.RUnlock()
+
+	var env interface{}
+	if envFunc != nil {
+		env = envFunc(e)
+	}
+
+	result, err := e.Binding.Run(env)
+	if err != nil {
+		return fmt.Errorf("expr '%s': %v", e.Binding.Expr, err)
+	}
+	if err, ok := result.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// prepare looks up the commands of a chain and converts their arguments.
+func (r *Registry) prepare(commands []types.Command) ([]*Call, []Handler, error) {
+	calls := make([]*Call, len(commands))
+	handlers := make([]Handler, len(commands))
+	for i, c := range commands {
+		cmd, ok := r.Lookup(c.Name)
+		if !ok {
+			return nil, nil, &UnknownCommandError{Command: c.Name}
+		}
+
+		args, err := convertArgs(cmd, c.Args)
+		if err != nil {
+			return nil, nil, err
+		}
+		calls[i] = &Call{Name: c.Name, Args: args}
+		handlers[i] = cmd.Handler
+	}
+	return calls, handlers, nil
+}
+
+// convertArgs checks the number of arguments against the command's ArgSpecs
+// and converts them to the declared types.
+func convertArgs(cmd Command, parsed []types.Arg) ([]interface{}, error) {
+	required := 0
+	for _, spec := range cmd.Args {
+		if !spec.Optional {
+			required++
+		}
+	}
+	if len(parsed) < required || len(parsed) > len(cmd.Args) {
+		return nil, fmt.Errorf("command '%s' takes %s, got %d", cmd.Name, argCount(required, len(cmd.Args)), len(parsed))
+	}
+
+	args := make([]interface{}, len(parsed))
+	for i, arg := range parsed {
+		value, ok := convertArg(cmd.Args[i].Type, arg)
+		if !ok {
+			return nil, fmt.Errorf("command '%s': argument '%s' must be %s, got '%s' at column %d", cmd.Name, cmd.Args[i].Name, cmd.Args[i].Type, arg.Raw, arg.Pos+1)
+		}
+		args[i] = value
+	}
+	return args, nil
+}
+
+// convertArg converts a parsed argument to argType. Any argument can be a
+// string, ints are also accepted as floats.
+func convertArg(argType ArgType, arg types.Arg) (interface{}, bool) {
+	switch argType {
+	case ArgString:
+		return arg.Raw, true
+	case ArgInt:
+		i, ok := arg.Value.(int)
+		return i, ok
+	case ArgFloat:
+		switch v := arg.Value.(type) {
+		case float64:
+			return v, true
+		case int:
+			return float64(v), true
+		}
+		return nil, false
+	case ArgBool:
+		b, ok := arg.Value.(bool)
+		return b, ok
+	}
+	return nil, false
+}
+
+func argCount(required, total int) string {
+	switch {
+	case required == total && total == 1:
+		return "1 argument"
+	case required == total:
+		return fmt.Sprintf("%d arguments", total)
+	default:
+		return fmt.Sprintf("%d to %d arguments", required, total)
+	}
+}
+
+// UnknownCommandError is returned for bindings to commands that aren't
+// registered.
+type UnknownCommandError struct {
+	Context string
+	Key     string
+	Command string
+}
+
+func (e *UnknownCommandError) Error() string {
+	if e.Context == "" {
+		return fmt.Sprintf("unknown command '%s'", e.Command)
+	}
+	return fmt.Sprintf("context '%s': key '%s' is bound to unknown command '%s'", e.Context, e.Key, e.Command)
+}
+
+// Validate checks that all bindings in config refer to registered commands
+// and pass valid arguments. Errors are reported in a stable order, the first
+// one is returned. Expression bindings are skipped, they are checked when
+// they are compiled.
+func (r *Registry) Validate(config types.Config) error {
+	contextNames := make([]string, 0, len(config))
+	for contextName := range config {
+		contextNames = append(contextNames, contextName)
+	}
+	sort.Strings(contextNames)
+
+	for _, contextName := range contextNames {
+		bindings := config[contextName].Bindings
+		keyNames := make([]string, 0, len(bindings))
+		for keyName := range bindings {
+			keyNames = append(keyNames, keyName)
+		}
+		sort.Strings(keyNames)
+
+		for _, keyName := range keyNames {
+			for _, binding := range bindings[keyName].Candidates() {
+				if binding.IsExpr() || binding.Unbind {
+					continue
+				}
+				commands, err := binding.Commands()
+				if err == nil {
+					_, _, err = r.prepare(commands)
+				}
+				var unknown *UnknownCommandError
+				if errors.As(err, &unknown) {
+					unknown.Context = contextName
+					unknown.Key = keyName
+					return unknown
+				} else if err != nil {
+					return fmt.Errorf("context '%s': key '%s': %v", contextName, keyName, err)
+				}
+			}
+		}
+	}
+	return nil
+}
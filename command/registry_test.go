@@ -0,0 +1,229 @@
+package command_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/spezifisch/tview-command/command"
+	"github.com/spezifisch/tview-command/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func nopHandler(call *command.Call) error {
+	return nil
+}
+
+func testRegistry(calls *[]*command.Call) *command.Registry {
+	record := func(call *command.Call) error {
+		*calls = append(*calls, call)
+		return nil
+	}
+
+	registry := command.NewRegistry()
+	registry.MustRegister(command.Command{
+		Name:        "queue.deleteTrack",
+		Description: "Remove the selected track from the queue",
+		Handler:     record,
+	})
+	registry.MustRegister(command.Command{
+		Name:        "favoriteTrack",
+		Description: "Favorite the selected track",
+		Args:        []command.ArgSpec{{Name: "mode", Type: command.ArgString, Optional: true}},
+		Handler:     record,
+	})
+	registry.MustRegister(command.Command{
+		Name:        "volume",
+		Description: "Change the volume",
+		Args:        []command.ArgSpec{{Name: "delta", Type: command.ArgInt}},
+		Handler:     record,
+	})
+	return registry
+}
+
+func TestRegister_Invalid(t *testing.T) {
+	registry := command.NewRegistry()
+
+	assert.Error(t, registry.Register(command.Command{Name: "", Handler: nopHandler}), "Empty names should be rejected")
+	assert.Error(t, registry.Register(command.Command{Name: "two words", Handler: nopHandler}), "Names with spaces should be rejected")
+	assert.Error(t, registry.Register(command.Command{Name: "noHandler"}), "Commands need a handler")
+	assert.Error(t, registry.Register(command.Command{
+		Name:    "badArgs",
+		Args:    []command.ArgSpec{{Name: "a", Optional: true}, {Name: "b"}},
+		Handler: nopHandler,
+	}), "Required arguments can't follow optional ones")
+
+	require.NoError(t, registry.Register(command.Command{Name: "quit", Handler: nopHandler}))
+	assert.EqualError(t, registry.Register(command.Command{Name: "quit", Handler: nopHandler}), "command 'quit' is already registered")
+	assert.Panics(t, func() {
+		registry.MustRegister(command.Command{Name: "quit", Handler: nopHandler})
+	})
+}
+
+func TestCommands_Sorted(t *testing.T) {
+	var calls []*command.Call
+	registry := testRegistry(&calls)
+
+	var names []string
+	for _, cmd := range registry.Commands() {
+		names = append(names, cmd.Name)
+	}
+	assert.Equal(t, []string{"favoriteTrack", "queue.deleteTrack", "volume"}, names)
+
+	cmd, ok := registry.Lookup("volume")
+	assert.True(t, ok)
+	assert.Equal(t, "Change the volume", cmd.Description)
+}
+
+func TestDispatch(t *testing.T) {
+	var calls []*command.Call
+	registry := testRegistry(&calls)
+	config := types.Config{
+		"Queue": {Bindings: map[string]types.Binding{
+			"d": {Command: "queue.deleteTrack"},
+			"y": {Command: "favoriteTrack toggle"},
+			"+": {Command: "volume 5"},
+		}},
+	}
+
+	for _, r := range "dy+" {
+		event := types.FromEventKey(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone), &config)
+		require.NoError(t, event.LookupCommand("Queue"))
+		require.NoError(t, registry.Dispatch(event))
+	}
+
+	require.Len(t, calls, 3)
+	assert.Equal(t, "queue.deleteTrack", calls[0].Name)
+	assert.Empty(t, calls[0].Args)
+	assert.Equal(t, "d", calls[0].Event.KeyName)
+	assert.Equal(t, []interface{}{"toggle"}, calls[1].Args)
+	assert.Equal(t, []interface{}{5}, calls[2].Args, "Arguments should be converted to the declared type")
+}
+
+func TestDispatch_Errors(t *testing.T) {
+	var calls []*command.Call
+	registry := testRegistry(&calls)
+
+	unbound := &types.Event{KeyName: "x"}
+	assert.True(t, errors.Is(registry.Dispatch(unbound), command.ErrNotBound))
+
+	unknown := &types.Event{KeyName: "x", Command: "doesNotExist", IsBound: true}
+	var unknownErr *command.UnknownCommandError
+	assert.ErrorAs(t, registry.Dispatch(unknown), &unknownErr)
+	assert.Equal(t, "doesNotExist", unknownErr.Command)
+
+	badArg := &types.Event{KeyName: "+", Command: "volume up", IsBound: true}
+	assert.EqualError(t, registry.Dispatch(badArg), "command 'volume': argument 'delta' must be int, got 'up' at column 8")
+
+	missingArg := &types.Event{KeyName: "+", Command: "volume", IsBound: true}
+	assert.EqualError(t, registry.Dispatch(missingArg), "command 'volume' takes 1 argument, got 0")
+
+	handlerErr := errors.New("queue is empty")
+	registry.MustRegister(command.Command{Name: "failing", Handler: func(call *command.Call) error { return handlerErr }})
+	failing := &types.Event{KeyName: "f", Command: "failing", IsBound: true}
+	assert.Equal(t, handlerErr, registry.Dispatch(failing), "Handler errors should be passed on")
+
+	assert.Empty(t, calls)
+}
+
+func TestValidate(t *testing.T) {
+	var calls []*command.Call
+	registry := testRegistry(&calls)
+
+	valid := types.Config{
+		"Queue": {Bindings: map[string]types.Binding{"d": {Command: "queue.deleteTrack"}, "y": {Command: "favoriteTrack"}}},
+	}
+	assert.NoError(t, registry.Validate(valid))
+
+	unknown := types.Config{
+		"Queue": {Bindings: map[string]types.Binding{"d": {Command: "queue.deleteTrack"}, "m": {Command: "queue.moveTrack"}}},
+	}
+	assert.EqualError(t, registry.Validate(unknown), "context 'Queue': key 'm' is bound to unknown command 'queue.moveTrack'")
+
+	badArgs := types.Config{
+		"Queue": {Bindings: map[string]types.Binding{"y": {Command: "favoriteTrack on off"}}},
+	}
+	assert.EqualError(t, registry.Validate(badArgs), "context 'Queue': key 'y': command 'favoriteTrack' takes 0 to 1 arguments, got 2")
+}
+
+func TestDispatch_Chains(t *testing.T) {
+	var ran []string
+	fail := errors.New("failed")
+	registry := command.NewRegistry()
+	for _, name := range []string{"a", "b", "c"} {
+		name := name
+		registry.MustRegister(command.Command{Name: name, Handler: func(call *command.Call) error {
+			ran = append(ran, call.Name)
+			return nil
+		}})
+	}
+	registry.MustRegister(command.Command{Name: "fail", Handler: func(call *command.Call) error {
+		ran = append(ran, call.Name)
+		return fail
+	}})
+
+	tests := []struct {
+		value string
+		ran   []string
+		err   error
+	}{
+		{"a; b; c", []string{"a", "b", "c"}, nil},
+		{"a && b && c", []string{"a", "b", "c"}, nil},
+		{"fail; b", []string{"fail", "b"}, fail},
+		{"fail && b && c", []string{"fail"}, fail},
+		{"fail && b; c", []string{"fail", "c"}, fail},
+		{"a; fail && b", []string{"a", "fail"}, fail},
+		{"a; unknown", nil, &command.UnknownCommandError{Command: "unknown"}},
+	}
+
+	for _, tt := range tests {
+		ran = nil
+		event := &types.Event{KeyName: "x", Command: tt.value, IsBound: true}
+		err := registry.Dispatch(event)
+		assert.Equal(t, tt.err, err, "error of %q", tt.value)
+		assert.Equal(t, tt.ran, ran, "commands run by %q", tt.value)
+	}
+}
+
+func TestValidate_SyntaxError(t *testing.T) {
+	var calls []*command.Call
+	registry := testRegistry(&calls)
+
+	config := types.Config{
+		"Queue": {Bindings: map[string]types.Binding{"y": {Command: "favoriteTrack 'toggle"}}},
+	}
+	assert.EqualError(t, registry.Validate(config), "context 'Queue': key 'y': syntax error at column 15 of 'favoriteTrack 'toggle': unterminated quote")
+}
+
+func TestDispatch_Expr(t *testing.T) {
+	var calls []*command.Call
+	registry := testRegistry(&calls)
+	config := types.Config{
+		"Queue": {Bindings: map[string]types.Binding{
+			"y": {Expr: "favoriteTrack(CurrentTrackID)"},
+			"e": {Expr: "fail()"},
+		}},
+	}
+
+	var favorited []string
+	registry.SetExprEnv(func(e *types.Event) interface{} {
+		return map[string]interface{}{
+			"CurrentTrackID": "12345",
+			"favoriteTrack":  func(trackID string) bool { favorited = append(favorited, trackID); return true },
+			"fail":           func() error { return errors.New("no track selected") },
+		}
+	})
+
+	event := types.FromEventKey(tcell.NewEventKey(tcell.KeyRune, 'y', tcell.ModNone), &config)
+	require.NoError(t, event.LookupCommand("Queue"))
+	assert.NoError(t, registry.Dispatch(event))
+	assert.Equal(t, []string{"12345"}, favorited)
+
+	event = types.FromEventKey(tcell.NewEventKey(tcell.KeyRune, 'e', tcell.ModNone), &config)
+	require.NoError(t, event.LookupCommand("Queue"))
+	assert.EqualError(t, registry.Dispatch(event), "no track selected", "Errors returned by the expression should be passed on")
+
+	assert.NoError(t, registry.Validate(config), "Expressions aren't checked against the commands")
+	assert.Empty(t, calls, "Expressions don't run commands")
+}
@@ -21,17 +21,26 @@ func resolveContextInheritance(config *types.Config, contextName string, resolve
 
 	// Start with a fresh context
 	resolved := types.Context{
-		Bindings: make(map[string]string),
+		Bindings: make(map[string]types.Binding),
 		Settings: currentContext.Settings,
 	}
 
-	// Implicitly inherit from Default unless already inherited OR inheriting Empty block
-	if contextName != "Default" && !contains(currentContext.ContextAdd, "Default") && !contains(currentContext.ContextOverride, "Empty") {
+	// Sub-contexts implicitly inherit from their parent, which already has the
+	// bindings of Default. Both are skipped when inheriting the Empty block.
+	parentContext := config.Parent(contextName)
+	if parentContext != "" && !contains(currentContext.ContextOverride, "Empty") {
+		if err := resolveContextInheritance(config, parentContext, resolvedContexts); err != nil {
+			return err
+		}
+		mergeBindings(&resolved, contextName, resolvedContexts[parentContext], types.InheritParent)
+	} else if contextName != "Default" && !contains(currentContext.ContextAdd, "Default") &&
+		!contains(currentContext.ContextOverride, "Default") && !contains(currentContext.ContextOverride, "Empty") {
+		// Implicitly inherit from Default unless already inherited OR inheriting Empty block
 		if err := resolveContextInheritance(config, "Default", resolvedContexts); err != nil {
 			// There is no Default config section in this file, so skip inheriting that.
 		} else {
 			// Merge bindings from Default context
-			mergeBindings(&resolved, resolvedContexts["Default"])
+			mergeBindings(&resolved, contextName, resolvedContexts["Default"], types.InheritDefault)
 		}
 	}
 
@@ -41,7 +50,7 @@ func resolveContextInheritance(config *types.Config, contextName string, resolve
 			return err
 		}
 		// Merge bindings from parent context
-		mergeBindings(&resolved, resolvedContexts[parentContext])
+		mergeBindings(&resolved, contextName, resolvedContexts[parentContext], types.InheritAdd)
 	}
 
 	// Next, handle any context overrides via context_override
@@ -53,11 +62,18 @@ func resolveContextInheritance(config *types.Config, contextName string, resolve
 			return err
 		}
 		// Override bindings from parent context
-		overrideBindings(&resolved, resolvedContexts[parentContext])
+		overrideBindings(&resolved, contextName, resolvedContexts[parentContext], types.InheritOverride)
+	}
+
+	// Unbind the keys of context_remove_keys, the own bindings still apply
+	removed := types.Context{Bindings: make(map[string]types.Binding)}
+	for _, key := range currentContext.RemovedKeys() {
+		removed.Bindings[key] = types.Binding{Unbind: true}
 	}
+	overrideBindings(&resolved, contextName, removed, types.InheritOwn)
 
 	// Finally, add/override the current context's own bindings
-	overrideBindings(&resolved, currentContext)
+	overrideBindings(&resolved, contextName, currentContext, types.InheritOwn)
 
 	// Store the resolved context
 	resolvedContexts[contextName] = resolved
@@ -66,19 +82,37 @@ func resolveContextInheritance(config *types.Config, contextName string, resolve
 }
 
 // mergeBindings adds bindings from the parent context, without overriding existing ones.
-func mergeBindings(resolved *types.Context, parent types.Context) {
+// The bindings record that contextName got them from parent in the way via says.
+func mergeBindings(resolved *types.Context, contextName string, parent types.Context, via types.Inheritance) {
 	for key, action := range parent.Bindings {
-		if _, exists := resolved.Bindings[key]; !exists {
-			resolved.Bindings[key] = action
+		inherited := inherit(action, contextName, via)
+		if existing, exists := resolved.Bindings[key]; exists {
+			resolved.Bindings[key] = existing.Shadow(inherited)
+		} else {
+			resolved.Bindings[key] = inherited
 		}
 	}
 }
 
 // overrideBindings overrides or adds the bindings from the parent context to the current one.
-func overrideBindings(resolved *types.Context, parent types.Context) {
+func overrideBindings(resolved *types.Context, contextName string, parent types.Context, via types.Inheritance) {
 	for key, action := range parent.Bindings {
-		resolved.Bindings[key] = action // This will override existing bindings
+		inherited := inherit(action, contextName, via)
+		if existing, exists := resolved.Bindings[key]; exists {
+			inherited = inherited.Shadow(existing)
+		}
+		resolved.Bindings[key] = inherited // This will override existing bindings
+	}
+}
+
+// inherit returns the binding with a provenance step for contextName. The
+// context's own bindings start a new provenance.
+func inherit(binding types.Binding, contextName string, via types.Inheritance) types.Binding {
+	if via == types.InheritOwn {
+		binding.Provenance = nil
+		binding.Shadowed = nil
 	}
+	return binding.Inherited(contextName, via)
 }
 
 // Small helper function to check if the given context is already part of the inheritance list.
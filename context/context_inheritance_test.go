@@ -3,7 +3,9 @@ package context_test
 import (
 	"testing"
 
+	"github.com/gdamore/tcell/v2"
 	"github.com/spezifisch/tview-command/keybinding"
+	"github.com/spezifisch/tview-command/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -19,11 +21,11 @@ func TestContextAddLogic(t *testing.T) {
 	assert.NotNil(t, queueContext, "Queue context should be present")
 
 	// Test keybindings defined directly in Queue
-	assert.Equal(t, "queue.moveTrack", queueContext.Bindings["m"], "Queue-specific binding for key 'm' should be moveTrack")
+	assert.Equal(t, "queue.moveTrack", queueContext.Bindings["m"].Command, "Queue-specific binding for key 'm' should be moveTrack")
 
 	// Ensure that bindings from both Default and ListPreset are inherited
-	assert.Equal(t, "goToTop", queueContext.Bindings["g"], "Queue context should inherit key 'g' from ListPreset")
-	assert.Equal(t, "addToQueue", queueContext.Bindings["a"], "Queue context should inherit key 'a' from Default")
+	assert.Equal(t, "goToTop", queueContext.Bindings["g"].Command, "Queue context should inherit key 'g' from ListPreset")
+	assert.Equal(t, "addToQueue", queueContext.Bindings["a"].Command, "Queue context should inherit key 'a' from Default")
 
 	// Test that keys not defined or inherited do not exist
 	_, exists := queueContext.Bindings["x"]
@@ -43,12 +45,12 @@ func TestContextFallback(t *testing.T) {
 	queueContext := (*config)["Queue"]
 
 	// Ensure that bindings present in the Queue context are resolved
-	assert.Equal(t, "queue.moveTrack", queueContext.Bindings["m"], "Binding for key 'm' should be defined in Queue")
+	assert.Equal(t, "queue.moveTrack", queueContext.Bindings["m"].Command, "Binding for key 'm' should be defined in Queue")
 
 	// Check fallback to Default context for undefined bindings in Queue
 	defaultContext := (*config)["Default"]
-	assert.Equal(t, "deleteTrack", defaultContext.Bindings["d"], "Default binding for key 'd' should be deleteTrack")
-	assert.Equal(t, defaultContext.Bindings["d"], queueContext.Bindings["d"], "Queue should inherit 'd' from Default")
+	assert.Equal(t, "deleteTrack", defaultContext.Bindings["d"].Command, "Default binding for key 'd' should be deleteTrack")
+	assert.Equal(t, defaultContext.Bindings["d"].Command, queueContext.Bindings["d"].Command, "Queue should inherit 'd' from Default")
 }
 
 func TestContextAddInheritance(t *testing.T) {
@@ -62,8 +64,8 @@ func TestContextAddInheritance(t *testing.T) {
 	assert.NotNil(t, queueContext, "Queue context should be present")
 
 	// Ensure keybindings from Default are inherited in Queue
-	assert.Equal(t, "deleteTrack", queueContext.Bindings["d"], "Binding for key 'd' should be inherited from Default")
-	assert.Equal(t, "addToQueue", queueContext.Bindings["a"], "Binding for key 'a' should be inherited from Default")
+	assert.Equal(t, "deleteTrack", queueContext.Bindings["d"].Command, "Binding for key 'd' should be inherited from Default")
+	assert.Equal(t, "addToQueue", queueContext.Bindings["a"].Command, "Binding for key 'a' should be inherited from Default")
 }
 
 // Test that inheritance from Default happens when Empty is not part of ContextOverride
@@ -78,7 +80,7 @@ func TestContextInheritWithoutEmpty(t *testing.T) {
 	assert.NotNil(t, specificContext, "SpecificContext should be present")
 
 	// Test that bindings from Default are inherited
-	assert.Equal(t, "defaultAction", specificContext.Bindings["a"], "SpecificContext should inherit key 'a' from Default")
+	assert.Equal(t, "defaultAction", specificContext.Bindings["a"].Command, "SpecificContext should inherit key 'a' from Default")
 }
 
 // Test that inheritance from Default is skipped when Empty is part of ContextOverride
@@ -97,5 +99,149 @@ func TestContextSkipInheritWithEmpty(t *testing.T) {
 	assert.False(t, exists, "SpecificContext should not inherit key 'a' from Default when Empty is in ContextOverride")
 
 	// Ensure that bindings defined in SpecificContext exist
-	assert.Equal(t, "specificAction", specificContext.Bindings["b"], "SpecificContext should have its own binding for key 'b'")
+	assert.Equal(t, "specificAction", specificContext.Bindings["b"].Command, "SpecificContext should have its own binding for key 'b'")
+}
+
+func TestBindingProvenance(t *testing.T) {
+	config, err := keybinding.LoadConfig("../testdata/TestProvenance.toml")
+	require.NoError(t, err, "Config should load without error")
+
+	queueContext := (*config)["Queue"]
+	assert.Equal(t, "Queue", queueContext.Bindings["d"].Origin(), "Own bindings should originate in the context")
+	assert.Equal(t, "ListPreset", queueContext.Bindings["g"].Origin())
+	assert.Equal(t, "Queue <-add- ListPreset", queueContext.Bindings["g"].ProvenanceString())
+	assert.Equal(t, "Queue <-override- ArticlePreset <-default- Default", queueContext.Bindings["a"].ProvenanceString(), "ArticlePreset inherits a from Default and overrides ListPreset's")
+
+	chain, err := config.Explain("Queue", "d")
+	require.NoError(t, err)
+	require.Len(t, chain, 3, "d should shadow the bindings of ArticlePreset and Default")
+	assert.Equal(t, "queue.deleteTrack", chain[0].Command)
+	assert.Equal(t, []types.Step{{Context: "Queue", Via: types.InheritOwn}}, chain[0].Provenance)
+	assert.Equal(t, "article.delete", chain[1].Command)
+	assert.Equal(t, "Queue <-override- ArticlePreset", chain[1].ProvenanceString())
+	assert.Equal(t, "deleteTrack", chain[2].Command)
+	assert.Equal(t, "Default", chain[2].Origin(), "Default's binding should be listed once, although every context inherits it")
+
+	chain, err = config.Explain("Queue", "a")
+	require.NoError(t, err)
+	require.Len(t, chain, 2, "a should shadow ListPreset's binding")
+	assert.Equal(t, "addToQueue", chain[0].Command)
+	assert.Equal(t, "list.selectAll", chain[1].Command)
+	assert.Equal(t, "Queue <-add- ListPreset", chain[1].ProvenanceString())
+
+	chain, err = config.Explain("Queue", "x")
+	assert.NoError(t, err)
+	assert.Empty(t, chain, "Unbound keys have nothing to explain")
+
+	_, err = config.Explain("NotAContext", "d")
+	assert.EqualError(t, err, "context 'NotAContext' not found")
+}
+
+func TestUnbindKeys(t *testing.T) {
+	config, err := keybinding.LoadConfig("../testdata/TestUnbindKeys.toml")
+	require.NoError(t, err, "Config should load without error")
+
+	queueContext := (*config)["Queue"]
+	assert.True(t, queueContext.Bindings["d"].Unbind, "d = false should unbind d")
+	assert.True(t, queueContext.Bindings["x"].Unbind, "x = \"nop\" should unbind x")
+	assert.Equal(t, "addToQueue", queueContext.Bindings["a"].Command, "Other keys should still be inherited")
+
+	chain, err := config.Explain("Queue", "d")
+	require.NoError(t, err)
+	require.Len(t, chain, 2)
+	assert.True(t, chain[0].Unbind)
+	assert.Equal(t, "deleteTrack", chain[1].Command, "The unbound binding should be shadowed")
+
+	textField := (*config)["TextField"]
+	assert.Equal(t, "closeModal", textField.Bindings["ESC"].Command, "Non-letter keys should be inherited")
+	assert.Equal(t, "submitText", textField.Bindings["Enter"].Command)
+	for _, key := range []string{"a", "d", "q", "Z"} {
+		assert.True(t, textField.Bindings[key].Unbind, "Letter %s should be unbound", key)
+	}
+}
+
+func TestUnbindKeys_Lookup(t *testing.T) {
+	config, err := keybinding.LoadConfig("../testdata/TestUnbindKeys.toml")
+	require.NoError(t, err, "Config should load without error")
+
+	stack := types.NewContextStack()
+	stack.Push("TextField")
+
+	expected := map[*tcell.EventKey]string{
+		tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone): "",
+		tcell.NewEventKey(tcell.KeyRune, 'q', tcell.ModNone): "",
+		tcell.NewEventKey(tcell.KeyEsc, 0, tcell.ModNone):    "closeModal",
+		tcell.NewEventKey(tcell.KeyCtrlC, 0, tcell.ModCtrl):  "copy",
+	}
+	for ev, command := range expected {
+		event := types.FromEventKey(ev, config)
+		_, err := event.LookupStack(stack)
+		assert.NoError(t, err)
+		assert.Equal(t, command != "", event.IsBound, "Key %s", event.KeyName)
+		assert.Equal(t, command, event.Command, "Unbound keys should not fall through to Global: %s", event.KeyName)
+	}
+
+	event := types.FromEventKey(tcell.NewEventKey(tcell.KeyRune, 'd', tcell.ModNone), config)
+	assert.NoError(t, event.LookupCommand("Queue"))
+	assert.False(t, event.IsBound, "d should not match in Queue")
+}
+
+func TestSubContexts(t *testing.T) {
+	config, err := keybinding.LoadConfig("../testdata/TestSubContexts.toml")
+	require.NoError(t, err, "Config should load without error")
+
+	trackList, exists := (*config)["Playlist.TrackList"]
+	require.True(t, exists, "Sub-context Playlist.TrackList should be loaded")
+	assert.Equal(t, "playlist.deleteTrack", trackList.Bindings["d"].Command, "Own bindings should win over inherited ones")
+	assert.Equal(t, "playlist.new", trackList.Bindings["n"].Command, "n should be inherited from Playlist")
+	assert.Equal(t, "goToTop", trackList.Bindings["g"].Command, "g should be inherited from Playlist's context_add")
+	assert.Equal(t, "quit", trackList.Bindings["q"].Command, "q should be inherited from Default through Playlist")
+	assert.Equal(t, "Playlist.TrackList <-parent- Playlist <-default- Default", trackList.Bindings["q"].ProvenanceString())
+
+	albumList, exists := (*config)["Browser.AlbumList"]
+	require.True(t, exists, "The table of Filter should create the implicit context Browser.AlbumList")
+	assert.Equal(t, "Browser", config.Parent("Browser.AlbumList"))
+	assert.Equal(t, "viewArtist", albumList.Bindings["v"].Command, "Browser.AlbumList should inherit from Browser")
+
+	filter, exists := (*config)["Browser.AlbumList.Filter"]
+	require.True(t, exists, "Sub-context Browser.AlbumList.Filter should be loaded")
+	assert.Equal(t, "Browser.AlbumList", config.Parent("Browser.AlbumList.Filter"))
+	assert.Equal(t, "viewArtist", filter.Bindings["v"].Command, "Filter should inherit from Browser through Browser.AlbumList")
+	assert.Equal(t, "Browser.AlbumList.Filter <-parent- Browser.AlbumList <-parent- Browser", filter.Bindings["v"].ProvenanceString())
+	assert.Equal(t, "filter.cancel", filter.Bindings["ESC"].Command)
+
+	search := (*config)["Playlist.Search"]
+	assert.Len(t, search.Bindings, 1, "Inheriting Empty should also skip the parent")
+
+	event := types.FromEventKey(tcell.NewEventKey(tcell.KeyRune, 'r', tcell.ModNone), config)
+	assert.NoError(t, event.LookupCommand("Playlist.TrackList"))
+	assert.True(t, event.IsBound)
+	assert.Equal(t, "playlist.rename", event.Command, "Lookups in a sub-context should find the bindings of its parent")
+}
+
+func TestSubContextCycle(t *testing.T) {
+	_, err := keybinding.LoadConfig("../testdata/TestSubContextCycle.toml")
+	assert.Error(t, err, "A parent that adds its own sub-context should be a cycle")
+	assert.Contains(t, err.Error(), "cyclic dependency")
+}
+
+func TestContextOverrideDefault(t *testing.T) {
+	config, err := keybinding.LoadConfigFromBytes("keys.toml", []byte(`
+[Default.bindings]
+q = "quit"
+
+[ListPreset.bindings]
+q = "list.close"
+g = "goToTop"
+
+[Queue]
+context_add = ["ListPreset"]
+context_override = ["Default"]
+`))
+	require.NoError(t, err, "Config should load without error")
+
+	queue := (*config)["Queue"]
+	assert.Equal(t, "quit", queue.Bindings["q"].Command, "Default should override ListPreset")
+	assert.Equal(t, "Queue <-override- Default", queue.Bindings["q"].ProvenanceString(), "Default should only be inherited through context_override")
+	assert.Equal(t, "goToTop", queue.Bindings["g"].Command)
 }
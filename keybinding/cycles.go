@@ -2,42 +2,154 @@ package keybinding
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/spezifisch/tview-command/log"
 	"github.com/spezifisch/tview-command/types"
 )
 
-func DetectCycleAndValidate(config types.Config) error {
-	visited := make(map[string]bool)
+// CycleError describes contexts that inherit from each other. Path starts
+// and ends with the same context, e.g. Queue, ListPreset, Queue.
+type CycleError struct {
+	Path []string
+}
 
-	var visit func(context string) error
-	visit = func(context string) error {
-		if visited[context] {
-			return fmt.Errorf("cyclic dependency detected")
-		}
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("cyclic dependency detected: %s", strings.Join(e.Path, " -> "))
+}
 
-		visited[context] = true
-		defer func() { visited[context] = false }()
+// UnknownContextError describes a context_add or context_override entry that
+// names a context which isn't in the config.
+type UnknownContextError struct {
+	Context   string
+	Field     string // "context_add" or "context_override"
+	Reference string
+}
+
+func (e *UnknownContextError) Error() string {
+	return fmt.Sprintf("context '%s': %s references unknown context '%s'", e.Context, e.Field, e.Reference)
+}
 
-		for _, nextContext := range config[context].ContextOverride {
-			if err := visit(nextContext); err != nil {
-				return err
+// DetectCycleAndValidate checks the inheritance graph of config before it is
+// resolved: every context_add and context_override entry must name a
+// context of the config, and no context may inherit from itself, directly or
+// through others. Sub-contexts inherit from their parent, see
+// types.Config.Parent.
+//
+// Every problem is logged and returned as ConfigErrors, unknown references
+// before cycles.
+func DetectCycleAndValidate(config types.Config) error {
+	var errs ConfigErrors
+	for _, contextName := range sortedContextNames(config) {
+		for _, edge := range inheritanceEdges(config, contextName) {
+			if _, exists := config[edge.context]; !exists {
+				errs = append(errs, &ConfigError{
+					Kind:    KindUnknownContext,
+					Context: contextName,
+					Field:   edge.field,
+					Err:     &UnknownContextError{Context: contextName, Field: edge.field, Reference: edge.context},
+				})
 			}
 		}
+	}
+	for _, cycle := range FindCycles(config) {
+		errs = append(errs, &ConfigError{Kind: KindCycle, Context: cycle[0], Err: &CycleError{Path: cycle}})
+	}
+
+	for _, err := range errs {
+		log.LogMessage("Error: " + err.Error())
+	}
+	return errs.err()
+}
+
+// FindCycles returns the inheritance cycles of config as paths that start
+// and end with the same context. It is a depth-first search that visits
+// every context and every reference once, each reference back to a context
+// on the current path closes one cycle. References to unknown contexts are
+// ignored.
+func FindCycles(config types.Config) [][]string {
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	state := make(map[string]int, len(config))
+	var path []string
+	var cycles [][]string
 
-		for _, nextContext := range config[context].ContextAdd {
-			if err := visit(nextContext); err != nil {
-				return err
+	var visit func(contextName string)
+	visit = func(contextName string) {
+		state[contextName] = onPath
+		path = append(path, contextName)
+
+		for _, edge := range inheritanceEdges(config, contextName) {
+			if _, exists := config[edge.context]; !exists {
+				continue
+			}
+			switch state[edge.context] {
+			case unvisited:
+				visit(edge.context)
+			case onPath:
+				start := len(path) - 1
+				for path[start] != edge.context {
+					start--
+				}
+				cycle := append(append([]string(nil), path[start:]...), edge.context)
+				cycles = append(cycles, cycle)
 			}
 		}
 
-		return nil
+		path = path[:len(path)-1]
+		state[contextName] = done
 	}
 
-	for context := range config {
-		if err := visit(context); err != nil {
-			return err
+	for _, contextName := range sortedContextNames(config) {
+		if state[contextName] == unvisited {
+			visit(contextName)
+		}
+	}
+	return cycles
+}
+
+type inheritanceEdge struct {
+	context string
+	field   string
+}
+
+// inheritanceEdges returns the contexts that contextName inherits from, in
+// the order they are resolved: the parent of a sub-context or else Default,
+// then context_add and context_override. The Empty block isn't a context.
+//
+// Like the resolver, a context without parent inherits Default implicitly,
+// unless it is Default, lists Default in context_add or context_override, or
+// inherits the Empty block.
+func inheritanceEdges(config types.Config, contextName string) []inheritanceEdge {
+	context := config[contextName]
+	skipsParent := contains(context.ContextOverride, "Empty")
+
+	var edges []inheritanceEdge
+	if parent := config.Parent(contextName); parent != "" && !skipsParent {
+		edges = append(edges, inheritanceEdge{context: parent, field: "parent"})
+	} else if _, hasDefault := config["Default"]; hasDefault && parent == "" && !skipsParent &&
+		contextName != "Default" && !contains(context.ContextAdd, "Default") && !contains(context.ContextOverride, "Default") {
+		edges = append(edges, inheritanceEdge{context: "Default", field: "default"})
+	}
+	for _, name := range context.ContextAdd {
+		edges = append(edges, inheritanceEdge{context: name, field: "context_add"})
+	}
+	for _, name := range context.ContextOverride {
+		if name != "Empty" {
+			edges = append(edges, inheritanceEdge{context: name, field: "context_override"})
 		}
 	}
+	return edges
+}
 
-	return nil
+func contains(contexts []string, context string) bool {
+	for _, c := range contexts {
+		if c == context {
+			return true
+		}
+	}
+	return false
 }
@@ -1,23 +1,54 @@
 package keybinding_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/spezifisch/tview-command/keybinding"
 	"github.com/spezifisch/tview-command/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDetectCycle_NoCycle(t *testing.T) {
 	config := types.Config{
 		"Default": {ContextOverride: []string{"Global"}},
-		"Global":  {ContextOverride: []string{}},
+		// without Empty, Global would inherit Default implicitly
+		"Global": {ContextOverride: []string{"Empty"}},
 	}
 
 	err := keybinding.DetectCycleAndValidate(config)
 	assert.NoError(t, err, "Config without cycles should not return an error")
 }
 
+func TestDetectCycle_ImplicitDefault(t *testing.T) {
+	config := types.Config{
+		"Preset":  {Bindings: map[string]types.Binding{"q": {Command: "quit"}}},
+		"Default": {ContextAdd: types.ContextNames{"Preset"}},
+	}
+
+	assert.Equal(t, [][]string{{"Default", "Preset", "Default"}}, keybinding.FindCycles(config), "Preset inherits Default implicitly")
+
+	config["Preset"] = types.Context{ContextOverride: types.ContextNames{"Empty"}}
+	assert.Empty(t, keybinding.FindCycles(config), "The Empty block drops the implicit Default")
+
+	config["Preset"] = types.Context{ContextOverride: types.ContextNames{"Default"}}
+	assert.Equal(t, [][]string{{"Default", "Preset", "Default"}}, keybinding.FindCycles(config), "Overriding with Default replaces the implicit Default")
+}
+
+func TestDetectCycle_ResolutionOrder(t *testing.T) {
+	config := types.Config{
+		"Artists":    {ContextAdd: types.ContextNames{"ListPreset"}, ContextOverride: types.ContextNames{"GridPreset"}},
+		"GridPreset": {ContextAdd: types.ContextNames{"Artists"}},
+		"ListPreset": {ContextAdd: types.ContextNames{"Artists"}},
+	}
+
+	assert.Equal(t, [][]string{
+		{"Artists", "ListPreset", "Artists"},
+		{"Artists", "GridPreset", "Artists"},
+	}, keybinding.FindCycles(config), "context_add is resolved before context_override")
+}
+
 func TestDetectCycle_SimpleCycle(t *testing.T) {
 	config := types.Config{
 		"Default": {ContextOverride: []string{"Global"}},
@@ -52,3 +83,62 @@ func TestDetectCycle_ContextAddCycle(t *testing.T) {
 	assert.Error(t, err, "Config with context_add cycles should return an error")
 	assert.Contains(t, err.Error(), "cyclic dependency", "Error message should indicate a cycle")
 }
+
+func TestDetectCycle_SubContextParent(t *testing.T) {
+	config := types.Config{
+		"Playlist":           {ContextOverride: []string{"Playlist.TrackList"}},
+		"Playlist.TrackList": {},
+	}
+
+	err := keybinding.DetectCycleAndValidate(config)
+	assert.Error(t, err, "A parent that inherits from its sub-context should return an error")
+	assert.Contains(t, err.Error(), "cyclic dependency", "Error message should indicate a cycle")
+}
+
+func TestDetectCycle_Path(t *testing.T) {
+	config := types.Config{
+		"ListPreset": {ContextAdd: []string{"Queue"}},
+		"Queue":      {ContextAdd: []string{"ListPreset"}},
+		"Modal":      {ContextOverride: []string{"Modal"}},
+	}
+
+	err := keybinding.DetectCycleAndValidate(config)
+	var cycleErr *keybinding.CycleError
+	require.ErrorAs(t, err, &cycleErr)
+	assert.Equal(t, []string{"ListPreset", "Queue", "ListPreset"}, cycleErr.Path)
+	assert.Equal(t, "cyclic dependency detected: ListPreset -> Queue -> ListPreset\ncyclic dependency detected: Modal -> Modal", err.Error(), "Every cycle should be reported")
+
+	assert.Equal(t, [][]string{
+		{"ListPreset", "Queue", "ListPreset"},
+		{"Modal", "Modal"},
+	}, keybinding.FindCycles(config), "Every cycle should be found")
+}
+
+func TestDetectCycle_UnknownContext(t *testing.T) {
+	config := types.Config{
+		"Queue":     {ContextAdd: []string{"ListPrest"}},
+		"TextField": {ContextOverride: []string{"Empty"}},
+	}
+
+	err := keybinding.DetectCycleAndValidate(config)
+	var unknownErr *keybinding.UnknownContextError
+	require.ErrorAs(t, err, &unknownErr, "The Empty block should not count as unknown")
+	assert.Equal(t, keybinding.UnknownContextError{Context: "Queue", Field: "context_add", Reference: "ListPrest"}, *unknownErr)
+	assert.Equal(t, "context 'Queue': context_add references unknown context 'ListPrest'", err.Error())
+}
+
+func TestDetectCycle_Diamonds(t *testing.T) {
+	// every layer adds both contexts of the next one, which takes 2^n steps
+	// when shared contexts are visited again for every path to them
+	config := types.Config{}
+	const layers = 40
+	for i := 0; i < layers; i++ {
+		next := []string{fmt.Sprintf("A%d", i+1), fmt.Sprintf("B%d", i+1)}
+		config[fmt.Sprintf("A%d", i)] = types.Context{ContextAdd: next}
+		config[fmt.Sprintf("B%d", i)] = types.Context{ContextAdd: next}
+	}
+	config[fmt.Sprintf("A%d", layers)] = types.Context{}
+	config[fmt.Sprintf("B%d", layers)] = types.Context{}
+
+	assert.NoError(t, keybinding.DetectCycleAndValidate(config))
+}
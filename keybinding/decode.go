@@ -0,0 +1,247 @@
+package keybinding
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/spezifisch/tview-command/types"
+)
+
+// contextFields are the keys of a context table that belong to the context
+// itself. Other tables in it are sub-contexts.
+var contextFields = map[string]bool{
+	"bindings":            true,
+	"context_add":         true,
+	"context_override":    true,
+	"context_remove_keys": true,
+	"settings":            true,
+}
+
+// UnknownFieldError describes an entry of a context that LoadConfig doesn't
+// understand, usually a typo.
+type UnknownFieldError struct {
+	Context    string
+	Field      string
+	Suggestion string
+}
+
+func (e *UnknownFieldError) Error() string {
+	msg := fmt.Sprintf("context '%s': unknown field '%s'", e.Context, e.Field)
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf(" (did you mean '%s'?)", e.Suggestion)
+	}
+	return msg
+}
+
+// decodedFile is the content of a config file.
+type decodedFile struct {
+	config   types.Config
+	unknown  []toml.Key // keys that aren't understood, see unknownFields
+	includes []string   // files to include, see loadIncludes
+}
+
+// decodeConfig decodes the contexts of a config file. Tables nested in a
+// context, like [Playlist.TrackList], are sub-contexts named with the
+// dotted path, if they contain context fields. Contexts that don't decode
+// are left out and reported. The top-level "include" entry isn't a context.
+//
+// The file is decoded twice. The tables of the first pass are only used to
+// find the contexts, so that the metadata of the second one knows which keys
+// weren't decoded. These are returned as unknown, see unknownFields.
+func decodeConfig(source string) (*decodedFile, ConfigErrors, error) {
+	var tables map[string]toml.Primitive
+	md, err := toml.Decode(source, &tables)
+	if err != nil {
+		return nil, nil, err
+	}
+	probe, err := toml.Decode(source, &map[string]toml.Primitive{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d := &decoder{md: md, probe: probe, config: make(types.Config)}
+	var includes []string
+	if include, exists := tables[includeField]; exists {
+		includes = d.decodeIncludes(include)
+		delete(tables, includeField)
+	}
+	d.decodeContexts(tables, nil)
+
+	// The headers of sub-contexts that only hold other tables aren't decoded
+	// themselves, neither are the tables of bindings, since Binding decodes
+	// them. Keys below an unknown key aren't interesting.
+	var unknown []toml.Key
+	for _, key := range md.Undecoded() {
+		if _, isContext := d.config[strings.Join(key, ".")]; isContext {
+			continue
+		}
+		length := contextLength(d.config, key)
+		if length == 0 || contextFields[key[length]] {
+			continue
+		}
+		if !hasAnyPrefix(key, unknown) {
+			unknown = append(unknown, key)
+		}
+	}
+	return &decodedFile{config: d.config, unknown: unknown, includes: includes}, d.errs, nil
+}
+
+type decoder struct {
+	md     toml.MetaData // decodes the contexts
+	probe  toml.MetaData // finds the tables in contexts
+	config types.Config
+	errs   ConfigErrors
+}
+
+func (d *decoder) decodeContexts(tables map[string]toml.Primitive, path []string) {
+	for _, name := range sortedTableNames(tables) {
+		table := tables[name]
+		contextPath := append(append([]string(nil), path...), name)
+		contextName := strings.Join(contextPath, ".")
+
+		if !d.isTable(contextPath) {
+			d.errs = append(d.errs, &ConfigError{Kind: KindParse, Context: contextName, Err: fmt.Errorf("context '%s' must be a table, got %s", contextName, strings.ToLower(d.md.Type(contextPath...)))})
+			continue
+		}
+		var fields map[string]toml.Primitive
+		if err := d.probe.PrimitiveDecode(table, &fields); err != nil {
+			d.errs = append(d.errs, &ConfigError{Kind: KindParse, Context: contextName, Err: fmt.Errorf("context '%s': %v", contextName, err)})
+			continue
+		}
+
+		var context types.Context
+		if err := d.md.PrimitiveDecode(table, &context); err != nil {
+			d.errs = append(d.errs, &ConfigError{Kind: KindParse, Context: contextName, Err: fmt.Errorf("context '%s': %v", contextName, err)})
+			continue
+		}
+		d.config[contextName] = context
+
+		subContexts := make(map[string]toml.Primitive)
+		for field, value := range fields {
+			if !contextFields[field] && d.isContextTable(append(append([]string(nil), contextPath...), field), value) {
+				subContexts[field] = value
+			}
+		}
+		d.decodeContexts(subContexts, contextPath)
+	}
+}
+
+// decodeIncludes decodes the file names of the include entry, a single name
+// or an array of them.
+func (d *decoder) decodeIncludes(include toml.Primitive) []string {
+	var value interface{}
+	if err := d.md.PrimitiveDecode(include, &value); err != nil {
+		d.errs = append(d.errs, &ConfigError{Kind: KindParse, Field: includeField, Err: fmt.Errorf("include: %v", err)})
+		return nil
+	}
+
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		includes := make([]string, len(v))
+		for i, entry := range v {
+			name, ok := entry.(string)
+			if !ok {
+				d.errs = append(d.errs, &ConfigError{Kind: KindParse, Field: includeField, Err: fmt.Errorf("include must be file names, got %v", entry)})
+				return nil
+			}
+			includes[i] = name
+		}
+		return includes
+	}
+	d.errs = append(d.errs, &ConfigError{Kind: KindParse, Field: includeField, Err: fmt.Errorf("include must be a file name or an array of file names, got %v", value)})
+	return nil
+}
+
+// isContextTable reports whether value at path is a table that can be a
+// context: an empty one, or one with context fields in it or in its tables.
+// Other tables, like a misspelled [Queue.bindngs], are unknown fields.
+func (d *decoder) isContextTable(path []string, value toml.Primitive) bool {
+	if !d.isTable(path) {
+		return false
+	}
+	var fields map[string]toml.Primitive
+	if err := d.probe.PrimitiveDecode(value, &fields); err != nil {
+		return false
+	}
+	if len(fields) == 0 {
+		return true
+	}
+	for field, fieldValue := range fields {
+		fieldPath := append(append([]string(nil), path...), field)
+		if contextFields[field] || d.isContextTable(fieldPath, fieldValue) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTable reports whether the value at path is a table. The decoder doesn't
+// know a type for tables that are only implied by a header like
+// [Playlist.TrackList.bindings].
+func (d *decoder) isTable(path []string) bool {
+	switch d.md.Type(path...) {
+	case "", "Hash":
+		return true
+	}
+	return false
+}
+
+// contextLength returns how many parts of key name the closest context of
+// config it is in, or 0 if it isn't in one.
+func contextLength(config types.Config, key toml.Key) int {
+	for length := len(key) - 1; length > 0; length-- {
+		if _, exists := config[strings.Join(key[:length], ".")]; exists {
+			return length
+		}
+	}
+	return 0
+}
+
+// unknownFields converts the unknown keys of decodeConfig to errors. Each
+// one belongs to the closest context it is in. Single field names get a
+// suggestion for the context field they probably mean.
+func unknownFields(config types.Config, keys []toml.Key) ConfigErrors {
+	names := make([]string, 0, len(contextFields))
+	for field := range contextFields {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+
+	var errs ConfigErrors
+	for _, key := range keys {
+		length := contextLength(config, key)
+		contextName := strings.Join(key[:length], ".")
+		field := strings.Join(key[length:], ".")
+
+		unknown := &UnknownFieldError{Context: contextName, Field: field}
+		if len(key)-length == 1 {
+			unknown.Suggestion = types.SuggestName(field, names)
+		}
+		errs = append(errs, &ConfigError{Kind: KindUnknownField, Context: contextName, Field: field, Err: unknown})
+	}
+	return errs
+}
+
+func sortedTableNames(tables map[string]toml.Primitive) []string {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// hasAnyPrefix reports whether key is one of prefixes or below one of them.
+func hasAnyPrefix(key toml.Key, prefixes []toml.Key) bool {
+	for _, prefix := range prefixes {
+		if len(key) >= len(prefix) && strings.Join(key[:len(prefix)], pathSeparator) == strings.Join(prefix, pathSeparator) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,281 @@
+package keybinding
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ErrorKind tells what is wrong with a config.
+type ErrorKind int
+
+const (
+	// KindRead means the config file couldn't be read.
+	KindRead ErrorKind = iota
+	// KindParse means the file isn't valid TOML, or a value has the wrong
+	// type.
+	KindParse
+	// KindUnknownContext means a context inherits from one that doesn't
+	// exist, see UnknownContextError.
+	KindUnknownContext
+	// KindCycle means contexts inherit from each other, see CycleError.
+	KindCycle
+	// KindInvalidKey means a key name can never be pressed, see
+	// InvalidKeyError.
+	KindInvalidKey
+	// KindDuplicateKey means a key is bound twice in the same context.
+	KindDuplicateKey
+	// KindInvalidSetting means a context setting has the wrong type.
+	KindInvalidSetting
+	// KindInvalidCommand means a command chain doesn't parse or has the
+	// wrong arguments.
+	KindInvalidCommand
+	// KindUnknownCommand means a binding refers to a command that isn't
+	// registered, see command.UnknownCommandError.
+	KindUnknownCommand
+	// KindInvalidExpression means an expression binding doesn't compile.
+	KindInvalidExpression
+	// KindInvalidCondition means a "when" condition doesn't compile.
+	KindInvalidCondition
+	// KindUnknownField means a context has an entry that isn't understood,
+	// see UnknownFieldError. It is only an error with WithStrict.
+	KindUnknownField
+	// KindInclude means an included file can't be loaded, or files include
+	// each other, see IncludeError.
+	KindInclude
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case KindRead:
+		return "read"
+	case KindParse:
+		return "parse"
+	case KindUnknownContext:
+		return "unknown context"
+	case KindCycle:
+		return "cycle"
+	case KindInvalidKey:
+		return "invalid key"
+	case KindDuplicateKey:
+		return "duplicate key"
+	case KindInvalidSetting:
+		return "invalid setting"
+	case KindInvalidCommand:
+		return "invalid command"
+	case KindUnknownCommand:
+		return "unknown command"
+	case KindInvalidExpression:
+		return "invalid expression"
+	case KindInvalidCondition:
+		return "invalid condition"
+	case KindUnknownField:
+		return "unknown field"
+	case KindInclude:
+		return "include"
+	}
+	return fmt.Sprintf("ErrorKind(%d)", int(k))
+}
+
+// Position is where something is defined in a config file. Line and Column
+// start at 1, they are 0 if unknown.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// String returns the position as "file:line:column", leaving out the parts
+// that are unknown.
+func (p Position) String() string {
+	var parts []string
+	if p.File != "" {
+		parts = append(parts, p.File)
+	}
+	if p.Line > 0 {
+		parts = append(parts, strconv.Itoa(p.Line))
+		if p.Column > 0 {
+			parts = append(parts, strconv.Itoa(p.Column))
+		}
+	}
+	return strings.Join(parts, ":")
+}
+
+// ConfigError is a problem with a config. Context, Field and Key say which
+// part of the config it is about, as far as that's known: Field is the
+// entry of the context, like "bindings" or "settings", and Key the entry in
+// that. Err is the error itself, e.g. an *InvalidKeyError.
+type ConfigError struct {
+	Kind ErrorKind
+	Position
+
+	Context string
+	Field   string
+	Key     string
+
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	if position := e.Position.String(); position != "" {
+		return position + ": " + e.Err.Error()
+	}
+	return e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// path returns the TOML key path the error is about. Errors about top-level
+// entries like include have a Field but no Context.
+func (e *ConfigError) path() []string {
+	if e.Context == "" {
+		if e.Field != "" {
+			return []string{e.Field}
+		}
+		return nil
+	}
+	path := strings.Split(e.Context, ".")
+	field := e.Field
+	if field == "" && e.Key != "" {
+		field = "bindings"
+	}
+	if field != "" {
+		path = append(path, field)
+		if e.Key != "" {
+			path = append(path, e.Key)
+		}
+	}
+	return path
+}
+
+// ConfigErrors are all problems found in a config. LoadConfig and the
+// Validate functions return them, so that every problem can be shown at
+// once. errors.As finds the first error of a type in the list.
+type ConfigErrors []*ConfigError
+
+func (errs ConfigErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Unwrap returns the errors of the list.
+func (errs ConfigErrors) Unwrap() []error {
+	unwrapped := make([]error, len(errs))
+	for i, err := range errs {
+		unwrapped[i] = err
+	}
+	return unwrapped
+}
+
+// As finds the first error in the list that matches target, see errors.As.
+func (errs ConfigErrors) As(target interface{}) bool {
+	for _, err := range errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Is reports whether an error in the list matches target, see errors.Is.
+func (errs ConfigErrors) Is(target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// add appends err to the list. ConfigErrors are appended one by one, other
+// errors as a ConfigError of kind.
+func (errs *ConfigErrors) add(kind ErrorKind, err error) {
+	if err == nil {
+		return
+	}
+	var list ConfigErrors
+	if errors.As(err, &list) {
+		*errs = append(*errs, list...)
+		return
+	}
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) {
+		configErr = &ConfigError{Kind: kind, Err: err}
+	}
+	*errs = append(*errs, configErr)
+}
+
+// err returns the list as an error, or nil if it is empty.
+func (errs ConfigErrors) err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// locate sets the positions of the errors that don't have one yet. Errors
+// that aren't found in positions are attributed to file.
+func (errs ConfigErrors) locate(file string, positions positions) {
+	for _, err := range errs {
+		if err.Line == 0 {
+			if position := positions.lookup(err.path()); position.Line > 0 {
+				err.Position = position
+			}
+		}
+		if err.File == "" {
+			err.File = file
+		}
+	}
+}
+
+// parseError is a toml.ParseError without the line number in its message,
+// since that is in the position of the ConfigError.
+type parseError struct {
+	toml.ParseError
+}
+
+func (e parseError) Error() string {
+	if e.Message == "" {
+		return e.ParseError.Error()
+	}
+	if e.LastKey != "" {
+		return fmt.Sprintf("toml: %s (last key '%s')", e.Message, e.LastKey)
+	}
+	return "toml: " + e.Message
+}
+
+func (e parseError) Unwrap() error {
+	return e.ParseError
+}
+
+// newParseError converts an error of the TOML decoder to a ConfigError with
+// the position of the problem in source.
+func newParseError(file, source string, err error) *ConfigError {
+	var tomlErr toml.ParseError
+	if !errors.As(err, &tomlErr) {
+		return &ConfigError{Kind: KindParse, Position: Position{File: file}, Err: err}
+	}
+
+	kind := KindParse
+	if strings.Contains(tomlErr.Message, "has already been defined") {
+		kind = KindDuplicateKey
+	}
+	line := tomlErr.Position.Line
+	column := 0
+	if start := tomlErr.Position.Start; start > 0 && start <= len(source) {
+		column = start - strings.LastIndex(source[:start], "\n")
+	}
+	return &ConfigError{
+		Kind:     kind,
+		Position: Position{File: file, Line: line, Column: column},
+		Err:      parseError{tomlErr},
+	}
+}
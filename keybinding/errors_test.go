@@ -0,0 +1,101 @@
+package keybinding_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spezifisch/tview-command/keybinding"
+	"github.com/spezifisch/tview-command/log"
+)
+
+func TestConfigErrors(t *testing.T) {
+	configPath := "../testdata/TestConfigErrors.toml"
+	config, err := keybinding.LoadConfig(configPath)
+	assert.Nil(t, config, "Config should be nil on error")
+
+	var errs keybinding.ConfigErrors
+	require.ErrorAs(t, err, &errs)
+
+	type problem struct {
+		Kind   keybinding.ErrorKind
+		Line   int
+		Column int
+	}
+	var problems []problem
+	for _, configErr := range errs {
+		assert.Equal(t, configPath, configErr.File)
+		problems = append(problems, problem{configErr.Kind, configErr.Line, configErr.Column})
+	}
+	assert.Equal(t, []problem{
+		{keybinding.KindInvalidKey, 3, 1},
+		{keybinding.KindInvalidSetting, 13, 1},
+		{keybinding.KindInvalidCommand, 9, 1},
+		{keybinding.KindInvalidExpression, 10, 1},
+		{keybinding.KindUnknownContext, 6, 1},
+	}, problems, "Every problem should be reported with its position")
+
+	assert.Contains(t, err.Error(), "../testdata/TestConfigErrors.toml:3:1: context 'Default': key 'CTRL-9' can never be pressed")
+	assert.Contains(t, err.Error(), "../testdata/TestConfigErrors.toml:6:1: context 'Queue': context_add references unknown context 'ListPrest'")
+
+	var invalidKey *keybinding.InvalidKeyError
+	assert.ErrorAs(t, err, &invalidKey, "errors.As should find the errors in the list")
+	var unknownContext *keybinding.UnknownContextError
+	assert.ErrorAs(t, err, &unknownContext)
+}
+
+func TestConfigErrors_Parse(t *testing.T) {
+	_, err := keybinding.LoadConfig("../testdata/TestParseError.toml")
+
+	var configErr *keybinding.ConfigError
+	require.ErrorAs(t, err, &configErr)
+	assert.Equal(t, keybinding.KindParse, configErr.Kind)
+	assert.Equal(t, keybinding.Position{File: "../testdata/TestParseError.toml", Line: 3, Column: 5}, configErr.Position)
+
+	var parseErr toml.ParseError
+	assert.ErrorAs(t, err, &parseErr, "The error of the decoder should be kept")
+}
+
+func TestConfigErrors_Read(t *testing.T) {
+	_, err := keybinding.LoadConfig("../testdata/NotThere.toml")
+
+	var configErr *keybinding.ConfigError
+	require.ErrorAs(t, err, &configErr)
+	assert.Equal(t, keybinding.KindRead, configErr.Kind)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestUnknownFields(t *testing.T) {
+	var messages []string
+	log.SetLogHandler(func(message string) {
+		messages = append(messages, message)
+	})
+	defer log.SetLogHandler(nil)
+
+	configPath := "../testdata/TestUnknownFields.toml"
+	config, err := keybinding.LoadConfig(configPath)
+	require.NoError(t, err, "Unknown fields should only warn")
+	assert.Contains(t, *config, "Playlist.TrackList", "Sub-contexts should not count as unknown")
+	assert.NotContains(t, *config, "Queue.bindngs", "A misspelled table should not become a sub-context")
+
+	output := strings.Join(messages, "\n")
+	assert.Contains(t, output, "Warning: ../testdata/TestUnknownFields.toml:5:1: context 'Queue': unknown field 'contxt_add' (did you mean 'context_add'?)")
+	assert.Contains(t, output, "Warning: ../testdata/TestUnknownFields.toml:7:2: context 'Queue': unknown field 'bindngs' (did you mean 'bindings'?)")
+	assert.NotContains(t, output, "bindngs.d", "Keys below an unknown field should not be reported again")
+
+	_, err = keybinding.LoadConfig(configPath, keybinding.WithStrict())
+	var errs keybinding.ConfigErrors
+	require.ErrorAs(t, err, &errs, "Unknown fields should be errors in strict mode")
+	require.Len(t, errs, 2)
+	for _, configErr := range errs {
+		assert.Equal(t, keybinding.KindUnknownField, configErr.Kind)
+	}
+	var unknown *keybinding.UnknownFieldError
+	require.ErrorAs(t, err, &unknown)
+	assert.Equal(t, keybinding.UnknownFieldError{Context: "Queue", Field: "contxt_add", Suggestion: "context_add"}, *unknown)
+}
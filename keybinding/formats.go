@@ -0,0 +1,374 @@
+package keybinding
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the syntax of a config file.
+type Format string
+
+const (
+	FormatTOML Format = "toml"
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+)
+
+// FormatOf returns the format of the config file at path by its extension.
+// Files with other extensions are TOML.
+func FormatOf(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".json":
+		return FormatJSON
+	}
+	return FormatTOML
+}
+
+// WithFormat makes LoadConfig read config files as format, whatever their
+// extension. Sources with a Format of their own keep it.
+func WithFormat(format Format) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// sourceFormat returns the format to read source with.
+func sourceFormat(source Source, o *options) Format {
+	if source.Format != "" {
+		return source.Format
+	}
+	if o.format != "" {
+		return o.format
+	}
+	return FormatOf(source.Path)
+}
+
+// toTOML converts the content of a config file to TOML, so that every format
+// is decoded and checked the same way, and indexes the positions of its keys
+// in the original file.
+//
+// YAML and JSON are decoded to the same values the TOML decoder would give,
+// and encoded again. Null values have no TOML equivalent and are errors.
+func toTOML(format Format, file string, data []byte) (string, positions, *ConfigError) {
+	var tree map[string]interface{}
+	var index positions
+	var configErr *ConfigError
+	switch format {
+	case FormatTOML:
+		text := string(data)
+		return text, indexPositions(file, text), nil
+	case FormatYAML:
+		tree, index, configErr = decodeYAML(file, data)
+	case FormatJSON:
+		tree, index, configErr = decodeJSON(file, data)
+	default:
+		return "", nil, &ConfigError{Kind: KindRead, Position: Position{File: file}, Err: fmt.Errorf("unknown config format '%s'", format)}
+	}
+	if configErr != nil {
+		return "", nil, configErr
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(tree); err != nil {
+		return "", nil, &ConfigError{Kind: KindParse, Position: Position{File: file}, Err: err}
+	}
+	return buf.String(), index, nil
+}
+
+// yamlLine matches the line number in the messages of the YAML decoder.
+var yamlLine = regexp.MustCompile(`^yaml: line (\d+): `)
+
+// decodeYAML decodes a YAML config file to a table and the positions of its
+// keys. Anchors, aliases and merge keys are resolved.
+func decodeYAML(file string, data []byte) (map[string]interface{}, positions, *ConfigError) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		position := Position{File: file}
+		message := err.Error()
+		if match := yamlLine.FindStringSubmatch(message); match != nil {
+			position.Line, _ = strconv.Atoi(match[1])
+			message = "yaml: " + message[len(match[0]):]
+		}
+		return nil, nil, &ConfigError{Kind: KindParse, Position: position, Err: errors.New(message)}
+	}
+
+	d := &yamlDecoder{file: file, index: make(positions)}
+	tree := make(map[string]interface{})
+	if len(document.Content) == 0 {
+		return tree, d.index, nil
+	}
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, nil, d.error(root, fmt.Errorf("yaml: config must be a mapping of contexts"))
+	}
+	value, err := d.decode(root, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return value.(map[string]interface{}), d.index, nil
+}
+
+type yamlDecoder struct {
+	file  string
+	index positions
+}
+
+func (d *yamlDecoder) decode(node *yaml.Node, path []string) (interface{}, *ConfigError) {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return d.decode(node.Alias, path)
+
+	case yaml.MappingNode:
+		table := make(map[string]interface{}, len(node.Content)/2)
+		var merges []*yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			if keyNode.Kind == yaml.ScalarNode && keyNode.Tag == "!!merge" {
+				merges = append(merges, valueNode)
+				continue
+			}
+			if keyNode.Kind != yaml.ScalarNode {
+				return nil, d.error(keyNode, fmt.Errorf("yaml: key in '%s' must be a scalar", strings.Join(path, ".")))
+			}
+			key := keyNode.Value
+			keyPath := append(append([]string(nil), path...), key)
+			if _, exists := table[key]; exists {
+				return nil, d.duplicate(keyNode, keyPath)
+			}
+			d.index.addPosition(keyPath, Position{File: d.file, Line: keyNode.Line, Column: keyNode.Column})
+
+			value, err := d.decode(valueNode, keyPath)
+			if err != nil {
+				return nil, err
+			}
+			table[key] = value
+		}
+		for _, merge := range merges {
+			if err := d.merge(table, merge, path); err != nil {
+				return nil, err
+			}
+		}
+		return table, nil
+
+	case yaml.SequenceNode:
+		list := make([]interface{}, len(node.Content))
+		for i, entry := range node.Content {
+			value, err := d.decode(entry, path)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = value
+		}
+		return list, nil
+
+	case yaml.ScalarNode:
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return nil, d.error(node, err)
+		}
+		switch v := value.(type) {
+		case nil:
+			return nil, d.error(node, fmt.Errorf("yaml: '%s' has no value", strings.Join(path, ".")))
+		case int:
+			return int64(v), nil
+		}
+		return value, nil
+	}
+	return nil, d.error(node, fmt.Errorf("yaml: unexpected node in '%s'", strings.Join(path, ".")))
+}
+
+// merge adds the entries of the mappings that a merge key "<<" refers to,
+// one mapping or a list of them, to table. Keys that table has already keep
+// their value, and of a list the first mapping that has a key wins.
+func (d *yamlDecoder) merge(table map[string]interface{}, node *yaml.Node, path []string) *ConfigError {
+	mappings := []*yaml.Node{node}
+	if node.Kind == yaml.SequenceNode {
+		mappings = node.Content
+	}
+	for _, mapping := range mappings {
+		for mapping.Kind == yaml.AliasNode {
+			mapping = mapping.Alias
+		}
+		if mapping.Kind != yaml.MappingNode {
+			return d.error(mapping, fmt.Errorf("yaml: merge in '%s' must be a mapping or a list of mappings", strings.Join(path, ".")))
+		}
+
+		value, err := d.decode(mapping, path)
+		if err != nil {
+			return err
+		}
+		for key, entry := range value.(map[string]interface{}) {
+			if _, exists := table[key]; !exists {
+				table[key] = entry
+			}
+		}
+	}
+	return nil
+}
+
+func (d *yamlDecoder) duplicate(node *yaml.Node, path []string) *ConfigError {
+	err := d.error(node, fmt.Errorf("yaml: key '%s' has already been defined", strings.Join(path, ".")))
+	err.Kind = KindDuplicateKey
+	return err
+}
+
+func (d *yamlDecoder) error(node *yaml.Node, err error) *ConfigError {
+	return &ConfigError{Kind: KindParse, Position: Position{File: d.file, Line: node.Line, Column: node.Column}, Err: err}
+}
+
+// decodeJSON decodes a JSON config file to a table and the positions of its
+// keys. Numbers become int64 if they are integers, float64 otherwise.
+func decodeJSON(file string, data []byte) (map[string]interface{}, positions, *ConfigError) {
+	d := &jsonDecoder{file: file, data: data, index: make(positions)}
+	d.decoder = json.NewDecoder(bytes.NewReader(data))
+	d.decoder.UseNumber()
+
+	token, err := d.decoder.Token()
+	if err == io.EOF {
+		return make(map[string]interface{}), d.index, nil
+	} else if err != nil {
+		return nil, nil, d.error(err)
+	}
+	if token != json.Delim('{') {
+		return nil, nil, d.errorAt(0, fmt.Errorf("json: config must be an object of contexts"))
+	}
+	tree, configErr := d.decodeObject(nil)
+	if configErr != nil {
+		return nil, nil, configErr
+	}
+	if _, err := d.decoder.Token(); err != io.EOF {
+		return nil, nil, d.errorAt(d.decoder.InputOffset(), fmt.Errorf("json: unexpected data after the config"))
+	}
+	return tree, d.index, nil
+}
+
+type jsonDecoder struct {
+	file    string
+	data    []byte
+	decoder *json.Decoder
+	index   positions
+}
+
+// decodeObject decodes the rest of an object whose '{' was read.
+func (d *jsonDecoder) decodeObject(path []string) (map[string]interface{}, *ConfigError) {
+	table := make(map[string]interface{})
+	for d.decoder.More() {
+		offset := d.keyOffset()
+		token, err := d.decoder.Token()
+		if err != nil {
+			return nil, d.error(err)
+		}
+		key := token.(string)
+		keyPath := append(append([]string(nil), path...), key)
+		if _, exists := table[key]; exists {
+			configErr := d.errorAt(offset, fmt.Errorf("json: key '%s' has already been defined", strings.Join(keyPath, ".")))
+			configErr.Kind = KindDuplicateKey
+			return nil, configErr
+		}
+		d.index.addPosition(keyPath, d.position(offset))
+
+		value, configErr := d.decodeValue(keyPath)
+		if configErr != nil {
+			return nil, configErr
+		}
+		table[key] = value
+	}
+	if _, err := d.decoder.Token(); err != nil {
+		return nil, d.error(err)
+	}
+	return table, nil
+}
+
+func (d *jsonDecoder) decodeValue(path []string) (interface{}, *ConfigError) {
+	offset := d.decoder.InputOffset()
+	token, err := d.decoder.Token()
+	if err != nil {
+		return nil, d.error(err)
+	}
+
+	switch value := token.(type) {
+	case json.Delim:
+		if value == '{' {
+			return d.decodeObject(path)
+		}
+		list := []interface{}{}
+		for d.decoder.More() {
+			entry, configErr := d.decodeValue(path)
+			if configErr != nil {
+				return nil, configErr
+			}
+			list = append(list, entry)
+		}
+		if _, err := d.decoder.Token(); err != nil {
+			return nil, d.error(err)
+		}
+		return list, nil
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i, nil
+		}
+		f, err := value.Float64()
+		if err != nil {
+			return nil, d.errorAt(offset, fmt.Errorf("json: %v", err))
+		}
+		return f, nil
+	case nil:
+		return nil, d.errorAt(d.skipSpace(offset), fmt.Errorf("json: '%s' has no value", strings.Join(path, ".")))
+	}
+	return token, nil
+}
+
+// keyOffset returns the offset of the next key, the decoder is before the
+// separator and the space in front of it.
+func (d *jsonDecoder) keyOffset() int64 {
+	return d.skipSpace(d.decoder.InputOffset())
+}
+
+func (d *jsonDecoder) skipSpace(offset int64) int64 {
+	for offset < int64(len(d.data)) && strings.IndexByte(" \t\r\n,:", d.data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+func (d *jsonDecoder) error(err error) *ConfigError {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return d.errorAt(syntaxErr.Offset, fmt.Errorf("json: %v", err))
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return d.errorAt(d.decoder.InputOffset(), fmt.Errorf("json: %v", err))
+}
+
+func (d *jsonDecoder) errorAt(offset int64, err error) *ConfigError {
+	return &ConfigError{Kind: KindParse, Position: d.position(offset), Err: err}
+}
+
+// position converts a byte offset in the file to a position.
+func (d *jsonDecoder) position(offset int64) Position {
+	if offset > int64(len(d.data)) {
+		offset = int64(len(d.data))
+	}
+	before := d.data[:offset]
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	return Position{
+		File:   d.file,
+		Line:   bytes.Count(before, []byte("\n")) + 1,
+		Column: utf8.RuneCount(before[lineStart:]) + 1,
+	}
+}
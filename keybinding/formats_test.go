@@ -0,0 +1,176 @@
+package keybinding_test
+
+import (
+	"testing"
+
+	"github.com/spezifisch/tview-command/keybinding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatOf(t *testing.T) {
+	assert.Equal(t, keybinding.FormatTOML, keybinding.FormatOf("config.toml"))
+	assert.Equal(t, keybinding.FormatYAML, keybinding.FormatOf("config.yaml"))
+	assert.Equal(t, keybinding.FormatYAML, keybinding.FormatOf("config.YML"))
+	assert.Equal(t, keybinding.FormatJSON, keybinding.FormatOf("keys/config.json"))
+	assert.Equal(t, keybinding.FormatTOML, keybinding.FormatOf("config"), "Files without a known extension should be TOML")
+}
+
+func TestLoadConfig_Formats(t *testing.T) {
+	expected, err := keybinding.LoadConfig("../testdata/TestFormats.toml")
+	require.NoError(t, err, "Config should load without error")
+
+	for _, path := range []string{"../testdata/TestFormats.yaml", "../testdata/TestFormats.json"} {
+		config, err := keybinding.LoadConfig(path)
+		require.NoError(t, err, "%s should load without error", path)
+		assert.Equal(t, expected, config, "%s should give the same config as TOML", path)
+	}
+
+	queue := (*expected)["Queue.Filter"]
+	assert.Equal(t, "search", queue.Bindings["/"].Command)
+	assert.Equal(t, []interface{}{"artist: Foo", 10, 1.5, true}, queue.Bindings["/"].Args)
+	assert.Equal(t, "copy", queue.Bindings["Ctrl+C"].Command, "Keys should be normalized")
+	assert.Len(t, queue.Bindings["SPC"].Alternatives, 2)
+	assert.True(t, queue.Bindings["x"].Unbind)
+}
+
+func TestLoadConfig_WithFormat(t *testing.T) {
+	data := []byte("Default:\n  bindings:\n    q: quit\n")
+
+	config, err := keybinding.LoadConfigFromBytes("keys", data, keybinding.WithFormat(keybinding.FormatYAML))
+	require.NoError(t, err, "WithFormat should override the extension")
+	assert.Equal(t, "quit", (*config)["Default"].Bindings["q"].Command)
+
+	_, err = keybinding.LoadConfigFromBytes("keys", data)
+	assert.Error(t, err, "Files without extension should be TOML")
+
+	_, err = keybinding.LoadConfigFromBytes("keys", data, keybinding.WithFormat("ini"))
+	assert.ErrorContains(t, err, "unknown config format 'ini'")
+}
+
+func TestLoadConfig_YAMLMerge(t *testing.T) {
+	data := []byte(`
+Default:
+  bindings: &common
+    q: quit
+    j: down
+ListPreset:
+  bindings: &list
+    g: goToTop
+    j: list.down
+Queue:
+  bindings:
+    j: queue.down
+    <<: *common
+Search:
+  context_override: [Empty]
+  bindings:
+    <<: [*list, *common]
+`)
+
+	config, err := keybinding.LoadConfigFromBytes("keys.yaml", data)
+	require.NoError(t, err, "Merge keys should load without error")
+
+	queue := (*config)["Queue"].Bindings
+	assert.Equal(t, "quit", queue["q"].Command, "Merged keys should be added")
+	assert.Equal(t, "queue.down", queue["j"].Command, "Explicit keys should override merged ones")
+
+	search := (*config)["Search"].Bindings
+	assert.Equal(t, "goToTop", search["g"].Command)
+	assert.Equal(t, "quit", search["q"].Command)
+	assert.Equal(t, "list.down", search["j"].Command, "The first mapping of a merge list should win")
+}
+
+func TestLoadConfig_FormatErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		kind     keybinding.ErrorKind
+		position keybinding.Position
+		message  string
+	}{
+		{
+			name:     "keys.yaml",
+			source:   "Default:\n  bindings:\n    \"CTRL-9\": quit\n",
+			kind:     keybinding.KindInvalidKey,
+			position: keybinding.Position{File: "keys.yaml", Line: 3, Column: 5},
+			message:  "context 'Default': key 'CTRL-9' can never be pressed",
+		},
+		{
+			name:     "keys.yaml",
+			source:   "Default:\n  bindings:\n    q: quit\n      r: exit\n",
+			kind:     keybinding.KindParse,
+			position: keybinding.Position{File: "keys.yaml", Line: 4},
+			message:  "keys.yaml:4: yaml: mapping values are not allowed in this context",
+		},
+		{
+			name:     "keys.yaml",
+			source:   "Default:\n  bindings:\n    q: quit\n    q: exit\n",
+			kind:     keybinding.KindDuplicateKey,
+			position: keybinding.Position{File: "keys.yaml", Line: 4},
+		},
+		{
+			name:     "keys.yaml",
+			source:   "Queue:\n  context_add:\n  bindings:\n    q: quit\n",
+			kind:     keybinding.KindParse,
+			position: keybinding.Position{File: "keys.yaml", Line: 2, Column: 15},
+			message:  "yaml: 'Queue.context_add' has no value",
+		},
+		{
+			name:     "keys.yaml",
+			source:   "Queue:\n  bindings:\n    <<: quit\n",
+			kind:     keybinding.KindParse,
+			position: keybinding.Position{File: "keys.yaml", Line: 3, Column: 9},
+			message:  "yaml: merge in 'Queue.bindings' must be a mapping or a list of mappings",
+		},
+		{
+			name:     "keys.json",
+			source:   "{\n  \"Default\": {\n    \"bindings\": {\n      \"CTRL-9\": \"quit\"\n    }\n  }\n}\n",
+			kind:     keybinding.KindInvalidKey,
+			position: keybinding.Position{File: "keys.json", Line: 4, Column: 7},
+			message:  "context 'Default': key 'CTRL-9' can never be pressed",
+		},
+		{
+			name:     "keys.json",
+			source:   "{\n  \"Default\": {\n    \"bindings\": {\"q\": \"quit\",}\n  }\n}\n",
+			kind:     keybinding.KindParse,
+			position: keybinding.Position{File: "keys.json", Line: 3, Column: 30},
+			message:  "json: invalid character",
+		},
+		{
+			name:     "keys.json",
+			source:   "{\"Default\": {\"bindings\": {\"q\": \"quit\", \"q\": \"exit\"}}}",
+			kind:     keybinding.KindDuplicateKey,
+			position: keybinding.Position{File: "keys.json", Line: 1, Column: 40},
+			message:  "json: key 'Default.bindings.q' has already been defined",
+		},
+		{
+			name:     "keys.json",
+			source:   "{\"Queue\": {\"context_add\": null}}",
+			kind:     keybinding.KindParse,
+			position: keybinding.Position{File: "keys.json", Line: 1, Column: 27},
+			message:  "json: 'Queue.context_add' has no value",
+		},
+		{
+			name:     "keys.json",
+			source:   "{\"Queue\": {\"context_add\": 42}}",
+			kind:     keybinding.KindParse,
+			position: keybinding.Position{File: "keys.json", Line: 1, Column: 2},
+			message:  "context names must be a string or an array of strings",
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := keybinding.LoadConfigFromBytes(tt.name, []byte(tt.source))
+		var errs keybinding.ConfigErrors
+		require.ErrorAs(t, err, &errs, tt.source)
+		require.Len(t, errs, 1, tt.source)
+		assert.Equal(t, tt.kind, errs[0].Kind, tt.source)
+		if tt.position.Column == 0 {
+			assert.Equal(t, tt.position.Line, errs[0].Line, tt.source)
+		} else {
+			assert.Equal(t, tt.position, errs[0].Position, tt.source)
+		}
+		assert.Contains(t, errs[0].Error(), tt.message, tt.source)
+	}
+}
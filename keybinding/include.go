@@ -0,0 +1,126 @@
+package keybinding
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spezifisch/tview-command/types"
+)
+
+// includeField is the top-level entry of a config file that names other
+// files to load with it:
+//
+//	include = ["presets/vim.toml", "local.toml"]
+const includeField = "include"
+
+// IncludeError describes an include entry whose file can't be loaded. Err
+// is the error of reading it, or an *IncludeCycleError.
+type IncludeError struct {
+	Include string
+	Err     error
+}
+
+func (e *IncludeError) Error() string {
+	return fmt.Sprintf("include '%s': %v", e.Include, e.Err)
+}
+
+func (e *IncludeError) Unwrap() error {
+	return e.Err
+}
+
+// IncludeCycleError describes config files that include each other. Files
+// starts and ends with the same file.
+type IncludeCycleError struct {
+	Files []string
+}
+
+func (e *IncludeCycleError) Error() string {
+	return fmt.Sprintf("include cycle detected: %s", strings.Join(e.Files, " -> "))
+}
+
+// include returns the source of a file that source includes. Relative names
+// are relative to the directory of source, in the same FS.
+func (s Source) include(name string) Source {
+	included := Source{Name: s.Name, Path: name, FS: s.FS}
+	if s.FS != nil {
+		included.Path = path.Join(path.Dir(s.Path), name)
+	} else if !filepath.IsAbs(name) {
+		included.Path = filepath.Join(filepath.Dir(s.Path), name)
+	}
+	return included
+}
+
+// includeLayers loads the files that file includes and merges file over
+// them. Included files are merged in the order they are listed, so later ones
+// override earlier ones, and the including file overrides all of them. They
+// can include files themselves. including are the files that include source,
+// to find cycles.
+func includeLayers(source Source, includes []string, file *layer, o *options, including []string) (*layer, ConfigErrors) {
+	chain := append(append([]string(nil), including...), source.Path)
+	position := file.positions.lookup([]string{includeField})
+	if position.File == "" {
+		position.File = source.Path
+	}
+
+	var errs ConfigErrors
+	merged := &layer{config: make(types.Config), positions: make(positions)}
+	for _, name := range includes {
+		included := source.include(name)
+
+		if cycle := includeCycle(chain, included.Path); cycle != nil {
+			errs = append(errs, &ConfigError{
+				Kind:     KindInclude,
+				Position: position,
+				Field:    includeField,
+				Err:      &IncludeError{Include: name, Err: &IncludeCycleError{Files: cycle}},
+			})
+			continue
+		}
+
+		includedLayer, includedErrs := loadLayer(included, o, chain)
+		merged.files = append(merged.files, included)
+		if includedLayer == nil {
+			// The file itself couldn't be read, blame the include entry
+			for _, err := range includedErrs {
+				var pathErr *fs.PathError
+				if err.Kind == KindRead && errors.As(err.Err, &pathErr) {
+					err.Kind = KindInclude
+					err.Position = position
+					err.Field = includeField
+					err.Err = &IncludeError{Include: name, Err: err.Err}
+				}
+			}
+			errs = append(errs, includedErrs...)
+			continue
+		}
+		errs = append(errs, includedErrs...)
+
+		mergeConfig(merged.config, includedLayer.config)
+		merged.positions.merge(includedLayer.positions)
+		merged.files = append(merged.files, includedLayer.files[1:]...)
+	}
+
+	mergeConfig(merged.config, file.config)
+	merged.positions.merge(file.positions)
+	merged.files = append(append([]Source(nil), file.files...), merged.files...)
+	return merged, errs
+}
+
+// includeCycle returns the cycle that including file from the end of chain
+// closes, or nil.
+func includeCycle(chain []string, file string) []string {
+	for i, including := range chain {
+		if samePath(including, file) {
+			return append(append([]string(nil), chain[i:]...), file)
+		}
+	}
+	return nil
+}
+
+func samePath(a, b string) bool {
+	return filepath.Clean(a) == filepath.Clean(b)
+}
@@ -0,0 +1,62 @@
+package keybinding_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/spezifisch/tview-command/keybinding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig_Include(t *testing.T) {
+	config, err := keybinding.LoadConfig("../testdata/TestInclude/main.toml")
+	require.NoError(t, err, "Config should load without error")
+
+	queue := (*config)["Queue"]
+	assert.Equal(t, "queue.deleteTrack", queue.Bindings["d"].Command, "The including file should override its includes")
+	assert.Equal(t, "list.next", queue.Bindings["j"].Command, "Later includes should override earlier ones")
+	assert.Equal(t, "list.first", queue.Bindings["g"].Command, "An include should override the files it includes")
+	assert.Equal(t, "list.up", queue.Bindings["k"].Command, "Includes should nest")
+	assert.Equal(t, "quit", queue.Bindings["q"].Command)
+}
+
+func TestLoadConfig_IncludeErrors(t *testing.T) {
+	_, err := keybinding.LoadConfig("../testdata/TestIncludeErrors/a.toml")
+	var errs keybinding.ConfigErrors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 3)
+
+	assert.Equal(t, keybinding.KindInvalidKey, errs[0].Kind)
+	assert.Equal(t, keybinding.Position{File: "../testdata/TestIncludeErrors/b.toml", Line: 4, Column: 1}, errs[0].Position, "Errors should name the included file")
+
+	var cycle *keybinding.IncludeCycleError
+	require.ErrorAs(t, errs[1], &cycle)
+	assert.Equal(t, keybinding.KindInclude, errs[1].Kind)
+	assert.Equal(t, keybinding.Position{File: "../testdata/TestIncludeErrors/b.toml", Line: 1, Column: 1}, errs[1].Position)
+	assert.Equal(t, "../testdata/TestIncludeErrors/b.toml:1:1: include 'a.toml': include cycle detected: ../testdata/TestIncludeErrors/a.toml -> ../testdata/TestIncludeErrors/b.toml -> ../testdata/TestIncludeErrors/a.toml", errs[1].Error())
+
+	assert.Equal(t, keybinding.KindInclude, errs[2].Kind)
+	assert.Equal(t, keybinding.Position{File: "../testdata/TestIncludeErrors/a.toml", Line: 1, Column: 1}, errs[2].Position, "A missing include should point to the include entry")
+	assert.ErrorContains(t, errs[2], "include 'missing.toml'")
+	assert.True(t, errors.Is(errs[2], fs.ErrNotExist))
+}
+
+func TestLoadConfigFS_Include(t *testing.T) {
+	fsys := fstest.MapFS{
+		"keys/main.toml":          {Data: []byte("include = [\"presets/list.toml\"]\n[Queue]\ncontext_add = [\"ListPreset\"]\n")},
+		"keys/presets/list.toml":  {Data: []byte("include = [\"../base.json\"]\n[ListPreset.bindings]\ng = \"goToTop\"\n")},
+		"keys/base.json":          {Data: []byte(`{"Default": {"bindings": {"q": "quit"}}}`)},
+		"keys/presets/other.toml": {Data: []byte("include = 42\n")},
+	}
+
+	config, err := keybinding.LoadConfigFS(fsys, "keys/main.toml")
+	require.NoError(t, err, "Includes should be read from the same FS")
+	assert.Equal(t, "goToTop", (*config)["Queue"].Bindings["g"].Command)
+	assert.Equal(t, "quit", (*config)["Queue"].Bindings["q"].Command)
+
+	_, err = keybinding.LoadConfigFS(fsys, "keys/presets/other.toml")
+	assert.ErrorContains(t, err, "keys/presets/other.toml:1:1: include must be a file name or an array of file names, got 42")
+}
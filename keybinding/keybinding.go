@@ -1,13 +1,11 @@
 package keybinding
 
 import (
-	"fmt"
-	"strings"
-
-	"github.com/BurntSushi/toml"
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
+	"errors"
+	"io"
+	"io/fs"
 
+	"github.com/spezifisch/tview-command/command"
 	tcContext "github.com/spezifisch/tview-command/context"
 	"github.com/spezifisch/tview-command/log"
 	"github.com/spezifisch/tview-command/types"
@@ -15,32 +13,141 @@ import (
 
 // LoadConfig loads a config.toml file from path,
 // validates the "keybinding graph", and parses it.
-func LoadConfig(path string) (*types.Config, error) {
-	var config types.Config
-	if _, err := toml.DecodeFile(path, &config); err != nil {
-		return nil, fmt.Errorf("toml.DecodeFile failed: %v", err)
+//
+// Files ending in .yaml, .yml or .json are read as YAML or JSON, with the
+// same structure as the TOML tables, see FormatOf and WithFormat.
+//
+// A file can include others with a top-level entry:
+//
+//	include = ["presets/vim.toml", "local.toml"]
+//
+// Paths are relative to the including file. The included files are loaded
+// first, in order, so each one overrides the ones before it, and the
+// including file overrides all of them. Includes can nest, files that
+// include each other are an error of kind KindInclude.
+//
+// If the config has problems, the error is ConfigErrors with all of them,
+// each with its position in the file where it's known.
+func LoadConfig(path string, opts ...Option) (*types.Config, error) {
+	return LoadLayers([]Source{{Path: path}}, opts...)
+}
+
+// LoadRawConfig loads the config file at path like LoadConfig, but doesn't
+// resolve inheritance, see LoadRawLayers.
+func LoadRawConfig(path string, opts ...Option) (types.Config, error) {
+	return LoadRawLayers([]Source{{Path: path}}, opts...)
+}
+
+// LoadConfigFS loads the config file at path in fsys, e.g. an embed.FS, like
+// LoadConfig.
+func LoadConfigFS(fsys fs.FS, path string, opts ...Option) (*types.Config, error) {
+	return LoadLayers([]Source{{Path: path, FS: fsys}}, opts...)
+}
+
+// LoadConfigFromBytes loads a config from data like LoadConfig. name is used
+// as the file name in errors, e.g. "defaults.toml".
+func LoadConfigFromBytes(name string, data []byte, opts ...Option) (*types.Config, error) {
+	if data == nil {
+		data = []byte{}
+	}
+	return LoadLayers([]Source{{Path: name, Data: data}}, opts...)
+}
+
+// LoadConfigFromReader reads a config from r and loads it like LoadConfig.
+// name is used as the file name in errors.
+func LoadConfigFromReader(name string, r io.Reader, opts ...Option) (*types.Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, ConfigErrors{{Kind: KindRead, Position: Position{File: name}, Err: err}}
+	}
+	return LoadConfigFromBytes(name, data, opts...)
+}
+
+// loadLayer reads and decodes one config file and runs all checks on it
+// that don't need the other layers. Keys are normalized. The files it
+// includes are merged below it, including are the files that include it.
+// The layer is nil if the file couldn't be read or is optional and missing.
+func loadLayer(source Source, o *options, including []string) (*layer, ConfigErrors) {
+	data, err := source.read()
+	if source.Optional && errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, ConfigErrors{{Kind: KindRead, Position: Position{File: source.Path}, Err: err}}
+	}
+	path := source.Path
+
+	// Other formats are converted to TOML, their errors point to the
+	// original file
+	format := sourceFormat(source, o)
+	text, positions, configErr := toTOML(format, path, data)
+	if configErr != nil {
+		return nil, ConfigErrors{configErr}
+	}
+	decoded, errs, err := decodeConfig(text)
+	if err != nil && format != FormatTOML {
+		return nil, ConfigErrors{{Kind: KindParse, Position: Position{File: path}, Err: err}}
+	} else if err != nil {
+		return nil, ConfigErrors{newParseError(path, text, err)}
+	}
+	config := decoded.config
+
+	// Unknown fields are usually typos, report them with their position
+	unknownErrs := unknownFields(config, decoded.unknown)
+	unknownErrs.locate(path, positions)
+	if o.strict {
+		errs = append(errs, unknownErrs...)
+	}
+	for _, unknownErr := range unknownErrs {
+		if o.strict {
+			log.LogMessage("Error: " + unknownErr.Error())
+		} else {
+			log.LogMessage("Warning: " + unknownErr.Error())
+		}
 	}
 
 	//log.Printf("Config: %+v\n", config)
 
-	// Validate the config for cycles and maybe other brokenness
-	if err := ValidateConfig(config); err != nil {
-		return nil, fmt.Errorf("ValidateConfig failed: %v", err)
+	// Validate what can be checked without the other layers, the
+	// inheritance graph is checked once they are merged
+	errs.add(KindInvalidKey, ValidateKeys(config))
+	errs.add(KindInvalidSetting, ValidateSettings(config))
+	errs.add(KindInvalidCommand, ValidateCommands(config))
+
+	// Compile expression bindings once, against the app's environment
+	errs.add(KindInvalidExpression, CompileExpressions(config, o.exprEnv))
+	errs.add(KindInvalidCondition, CompileConditions(config, o.state))
+
+	// Check the bound commands against the app's commands
+	if o.registry != nil {
+		errs.add(KindInvalidCommand, validateRegistry(o.registry, config))
 	}
+	errs.locate(path, positions)
 
-	// Check if config is essentially empty and warn if so
-	hasBindings := false
-	caser := cases.Title(language.English)
-	for _, context := range config {
-		for key, action := range context.Bindings {
-			// Convert "CTRL-L" (with minus and any case) to "Ctrl+L" (with plus)
-			if strings.Contains(key, "-") {
-				newKey := strings.ReplaceAll(key, "-", "+")
-				newKey = caser.String(strings.ToLower(newKey))
-				delete(context.Bindings, key)
-				context.Bindings[newKey] = action
+	// Normalize key names, so that the layers can be merged key by key
+	for contextName, context := range config {
+		context.Bindings = normalizeKeys(context.Bindings)
+		if source.Name != "" {
+			for keyName, binding := range context.Bindings {
+				context.Bindings[keyName] = withLayer(binding, source.Name)
 			}
 		}
+		config[contextName] = context
+	}
+
+	file := &layer{config: config, positions: positions, files: []Source{source}}
+	if len(decoded.includes) == 0 {
+		return file, errs
+	}
+	merged, includeErrs := includeLayers(source, decoded.includes, file, o, including)
+	return merged, append(errs, includeErrs...)
+}
+
+// resolveConfig resolves the inheritance of a merged config that passed
+// DetectCycleAndValidate.
+func resolveConfig(config types.Config) (*types.Config, error) {
+	// Check if config is essentially empty
+	hasBindings := false
+	for _, context := range config {
 		if len(context.Bindings) > 0 {
 			hasBindings = true
 		}
@@ -68,3 +175,49 @@ func LoadConfig(path string) (*types.Config, error) {
 	log.LogMessage("Config loaded.")
 	return &config, nil
 }
+
+// normalizeKeys converts the key names of bindings to their canonical form,
+// e.g. "CTRL-c", "ctrl+c", "C-c" and "<C-c>" all become "Ctrl+C", so that
+// they match the KeyName of events. Key sequences like "SPC  b s" are
+// normalized key by key. Keys that don't parse are kept as they are.
+//
+// Keys are converted in sorted order, so if two spellings of the same key
+// are bound the first one wins. ValidateKeys reports them as duplicates.
+func normalizeKeys(bindings map[string]types.Binding) map[string]types.Binding {
+	if bindings == nil {
+		return nil
+	}
+
+	normalized := make(map[string]types.Binding, len(bindings))
+	for _, keyName := range sortedKeyNames(bindings) {
+		normalizedName := types.CanonicalKeyName(keyName)
+		if _, exists := normalized[normalizedName]; !exists {
+			normalized[normalizedName] = bindings[keyName]
+		}
+	}
+	return normalized
+}
+
+// validateRegistry checks the commands of every binding against registry, see
+// command.Registry.Validate.
+func validateRegistry(registry *command.Registry, config types.Config) error {
+	var errs ConfigErrors
+	for _, contextName := range sortedContextNames(config) {
+		bindings := config[contextName].Bindings
+		for _, keyName := range sortedKeyNames(bindings) {
+			single := types.Config{contextName: {Bindings: map[string]types.Binding{keyName: bindings[keyName]}}}
+			err := registry.Validate(single)
+			if err == nil {
+				continue
+			}
+			kind := KindInvalidCommand
+			var unknown *command.UnknownCommandError
+			if errors.As(err, &unknown) {
+				kind = KindUnknownCommand
+			}
+			log.LogMessage("Error: " + err.Error())
+			errs = append(errs, &ConfigError{Kind: kind, Context: contextName, Key: keyName, Err: err})
+		}
+	}
+	return errs.err()
+}
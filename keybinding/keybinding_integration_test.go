@@ -70,7 +70,7 @@ func TestKeybindingIntegrationWithConfig(t *testing.T) {
 
 	if context, exists := (*config)["Default"]; exists {
 		for key, command := range context.Bindings {
-			output, err := executeExpression(key, command, env)
+			output, err := executeExpression(key, command.Command, env)
 			if err != nil {
 				t.Errorf("Error executing command %s: %v", command, err)
 			} else if output != expectedOutputs[key] {
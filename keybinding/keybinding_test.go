@@ -1,10 +1,19 @@
 package keybinding_test
 
 import (
+	"errors"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"testing/iotest"
 
+	"github.com/gdamore/tcell/v2"
+	"github.com/spezifisch/tview-command/command"
 	"github.com/spezifisch/tview-command/keybinding"
+	"github.com/spezifisch/tview-command/log"
+	"github.com/spezifisch/tview-command/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -17,6 +26,34 @@ func TestLoadConfig(t *testing.T) {
 	assert.NotEmpty(t, (*config)["Global"].Bindings, "Global context should have bindings")
 }
 
+func TestLoadConfig_Sources(t *testing.T) {
+	source := "[Global.bindings]\nESC = \"closeModal\"\n"
+
+	fromBytes, err := keybinding.LoadConfigFromBytes("inline.toml", []byte(source))
+	require.NoError(t, err, "Config should load from bytes")
+	fromReader, err := keybinding.LoadConfigFromReader("inline.toml", strings.NewReader(source))
+	require.NoError(t, err, "Config should load from a reader")
+	fromFS, err := keybinding.LoadConfigFS(fstest.MapFS{"keys/default.toml": {Data: []byte(source)}}, "keys/default.toml")
+	require.NoError(t, err, "Config should load from an fs.FS")
+
+	for _, config := range []*types.Config{fromBytes, fromReader, fromFS} {
+		assert.Equal(t, "closeModal", (*config)["Global"].Bindings["ESC"].Command)
+	}
+
+	_, err = keybinding.LoadConfigFromBytes("inline.toml", []byte("[Global.bindings]\n\"CTRL-9\" = \"quit\"\n"))
+	assert.ErrorContains(t, err, "inline.toml:2:1: context 'Global': key 'CTRL-9'", "Errors should name the source")
+
+	_, err = keybinding.LoadConfigFromReader("broken.toml", iotest.ErrReader(errors.New("disk on fire")))
+	var configErr *keybinding.ConfigError
+	require.ErrorAs(t, err, &configErr)
+	assert.Equal(t, keybinding.KindRead, configErr.Kind)
+	assert.Equal(t, "broken.toml", configErr.File)
+
+	empty, err := keybinding.LoadConfigFromBytes("empty.toml", nil)
+	require.NoError(t, err, "No data should be an empty config")
+	assert.Empty(t, *empty)
+}
+
 func TestGlobalContext(t *testing.T) {
 	configPath := "../testdata/TestGlobalContext.toml"
 	config, err := keybinding.LoadConfig(configPath)
@@ -30,7 +67,7 @@ func TestGlobalContext(t *testing.T) {
 
 	globalContext := (*config)["Global"]
 	for key, command := range expectedBindings {
-		assert.Equal(t, command, globalContext.Bindings[key], "Binding for key %s should be %s", key, command)
+		assert.Equal(t, command, globalContext.Bindings[key].Command, "Binding for key %s should be %s", key, command)
 	}
 }
 
@@ -49,7 +86,18 @@ func TestInvalidContextAddType(t *testing.T) {
 
 	// The test expects an error, as the TOML file has an invalid type for `context_add`
 	assert.Error(t, err, "Config should return an error for invalid context_add type")
-	assert.Contains(t, err.Error(), "incompatible types", "Error should indicate type mismatch")
+	assert.Contains(t, err.Error(), "context names must be a string or an array of strings", "Error should indicate type mismatch")
+}
+
+func TestContextAddString(t *testing.T) {
+	config, err := keybinding.LoadConfig("../testdata/TestContextAddString.toml")
+	require.NoError(t, err, "context_add and context_override should also be strings")
+
+	queue := (*config)["Queue"]
+	assert.Equal(t, "queue.AddTrack", queue.Bindings["a"].Command, "Comma-separated names should be split")
+	assert.Equal(t, "goToTop", queue.Bindings["g"].Command)
+
+	assert.Len(t, (*config)["Modal"].Bindings, 1, "Modal should only have its own binding")
 }
 
 func TestKeyBindings(t *testing.T) {
@@ -62,14 +110,14 @@ func TestKeyBindings(t *testing.T) {
 	defaultContext := (*config)["Default"]
 	assert.NotNil(t, defaultContext, "Default context should be present")
 
-	assert.Equal(t, "deleteTrack", defaultContext.Bindings["d"], "Binding for key 'd' should be 'deleteTrack'")
-	assert.Equal(t, "addToQueue", defaultContext.Bindings["a"], "Binding for key 'a' should be 'addToQueue'")
+	assert.Equal(t, "deleteTrack", defaultContext.Bindings["d"].Command, "Binding for key 'd' should be 'deleteTrack'")
+	assert.Equal(t, "addToQueue", defaultContext.Bindings["a"].Command, "Binding for key 'a' should be 'addToQueue'")
 
 	queueContext := (*config)["Queue"]
 	assert.NotNil(t, queueContext, "Queue context should be present")
 
-	assert.Equal(t, "queue.deleteTrack", queueContext.Bindings["d"], "Binding for key 'd' in Queue should be 'queue.deleteTrack'")
-	assert.Equal(t, "shuffleQueue", queueContext.Bindings["s"], "Binding for key 's' in Queue should be 'shuffleQueue'")
+	assert.Equal(t, "queue.deleteTrack", queueContext.Bindings["d"].Command, "Binding for key 'd' in Queue should be 'queue.deleteTrack'")
+	assert.Equal(t, "shuffleQueue", queueContext.Bindings["s"].Command, "Binding for key 's' in Queue should be 'shuffleQueue'")
 }
 
 func TestBigFile(t *testing.T) {
@@ -87,6 +135,14 @@ func TestContextAddErrors(t *testing.T) {
 	assert.Error(t, err, "Config should return an error for context_add errors")
 }
 
+func TestImplicitDefaultCycle(t *testing.T) {
+	config, err := keybinding.LoadConfig("../testdata/TestImplicitDefaultCycle.toml")
+	assert.Nil(t, config, "Config should be nil on error")
+	var cycle *keybinding.CycleError
+	require.ErrorAs(t, err, &cycle, "The implicit Default edge should be part of the cycle")
+	assert.Equal(t, []string{"Default", "Preset", "Default"}, cycle.Path)
+}
+
 func TestContextOverrideErrors(t *testing.T) {
 	configPath := "../testdata/TestContextOverrideErrors.toml"
 	config, err := keybinding.LoadConfig(configPath)
@@ -129,6 +185,7 @@ func TestInvalidKeyNames(t *testing.T) {
 	_, err := keybinding.LoadConfig(configPath)
 
 	assert.Error(t, err, "Config should return an error for invalid key names")
+	assert.Contains(t, err.Error(), "context 'Global': key 'CTRL-9'", "Error should name the context and key")
 }
 
 func TestMissingBindings(t *testing.T) {
@@ -180,9 +237,225 @@ func TestLoadConfig_ConvertKeyBindings(t *testing.T) {
 	assert.Contains(t, globalBindings, "Ctrl+V", "Global context should have converted 'Ctrl+V' binding")
 	assert.Contains(t, globalBindings, "Ctrl+X", "Global context should have converted 'Ctrl+X' binding")
 
-	assert.Equal(t, "closeModal", globalBindings["ESC"])
-	assert.Equal(t, "copy", globalBindings["Ctrl+C"])
-	assert.Equal(t, "paste", globalBindings["Ctrl+V"])
-	assert.Equal(t, "cut", globalBindings["Ctrl+X"])
-	assert.Equal(t, "undo", globalBindings["Ctrl+Z"])
+	assert.Equal(t, "closeModal", globalBindings["ESC"].Command)
+	assert.Equal(t, "copy", globalBindings["Ctrl+C"].Command)
+	assert.Equal(t, "paste", globalBindings["Ctrl+V"].Command)
+	assert.Equal(t, "cut", globalBindings["Ctrl+X"].Command)
+	assert.Equal(t, "undo", globalBindings["Ctrl+Z"].Command)
+}
+
+func TestLoadConfig_KeySpellingsMatchEvents(t *testing.T) {
+	configPath := "../testdata/TestKeySpellings.toml"
+	config, err := keybinding.LoadConfig(configPath)
+
+	assert.NoError(t, err, "Config should load without error")
+	assert.NotNil(t, config, "Config should not be nil")
+
+	expected := map[*tcell.EventKey]string{
+		tcell.NewEventKey(tcell.KeyCtrlA, 0, tcell.ModCtrl):  "selectAll",
+		tcell.NewEventKey(tcell.KeyCtrlE, 0, tcell.ModCtrl):  "endOfLine",
+		tcell.NewEventKey(tcell.KeyCtrlK, 0, tcell.ModCtrl):  "killLine",
+		tcell.NewEventKey(tcell.KeyCtrlU, 0, tcell.ModCtrl):  "clearText",
+		tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModNone): "openCommandPalette",
+		tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone):  "confirmAction",
+		tcell.NewEventKey(tcell.KeyEsc, 0, tcell.ModNone):    "closeModal",
+	}
+
+	for ev, command := range expected {
+		event := types.FromEventKey(ev, config)
+		assert.NoError(t, event.LookupCommand("Default"))
+		assert.True(t, event.IsBound, "Key %s should be bound", event.KeyName)
+		assert.Equal(t, command, event.Command, "Key %s should trigger %s", event.KeyName, command)
+	}
+}
+
+func TestLoadConfig_KeySequences(t *testing.T) {
+	configPath := "../testdata/TestKeySequences.toml"
+	config, err := keybinding.LoadConfig(configPath)
+
+	assert.NoError(t, err, "Config should load without error")
+	assert.NotNil(t, config, "Config should not be nil")
+
+	listBindings := (*config)["ListPreset"].Bindings
+	assert.Equal(t, "goToTop", listBindings["g g"].Command)
+	assert.Equal(t, "save", listBindings["Ctrl+X Ctrl+S"].Command, "Sequences should be normalized key by key")
+	assert.Equal(t, "badges.hide", listBindings["SPC B h"].Command, "ListPreset should inherit sequences from Default")
+
+	m := types.NewSequenceMatcher(config)
+	e := types.FromEventKey(tcell.NewEventKey(tcell.KeyRune, 'g', tcell.ModNone), config)
+	state, err := m.Feed(e, "ListPreset")
+	assert.NoError(t, err)
+	assert.Equal(t, types.SequencePending, state)
+
+	e = types.FromEventKey(tcell.NewEventKey(tcell.KeyRune, 'g', tcell.ModNone), config)
+	state, err = m.Feed(e, "ListPreset")
+	assert.NoError(t, err)
+	assert.Equal(t, types.SequenceMatched, state)
+	assert.Equal(t, "goToTop", e.Command)
+}
+
+func TestLoadConfig_OpaqueContext(t *testing.T) {
+	configPath := "../testdata/TestOpaqueContext.toml"
+	config, err := keybinding.LoadConfig(configPath)
+
+	assert.NoError(t, err, "Config should load without error")
+	assert.NotNil(t, config, "Config should not be nil")
+	assert.True(t, (*config)["Modal"].IsOpaque(), "Modal should keep its settings through resolution")
+
+	stack := types.NewContextStack()
+	stack.Push("Default")
+	stack.Push("Modal")
+
+	expected := map[*tcell.EventKey]string{
+		tcell.NewEventKey(tcell.KeyCtrlC, 0, tcell.ModCtrl):  "copy",
+		tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone):  "confirmAction",
+		tcell.NewEventKey(tcell.KeyCtrlQ, 0, tcell.ModCtrl):  "",
+		tcell.NewEventKey(tcell.KeyRune, 'd', tcell.ModNone): "",
+	}
+	for ev, command := range expected {
+		event := types.FromEventKey(ev, config)
+		_, err := event.LookupStack(stack)
+		assert.NoError(t, err)
+		assert.Equal(t, command, event.Command, "Unexpected command for key %s", event.KeyName)
+	}
+}
+
+func TestLoadConfig_WithRegistry(t *testing.T) {
+	registry := command.NewRegistry()
+	for _, name := range []string{"closeModal", "openCommandPalette"} {
+		registry.MustRegister(command.Command{Name: name, Handler: func(call *command.Call) error { return nil }})
+	}
+
+	config, err := keybinding.LoadConfig("../testdata/TestGlobalContext.toml", keybinding.WithRegistry(registry))
+	assert.NoError(t, err, "Config should load when all commands are registered")
+	assert.NotNil(t, config)
+
+	config, err = keybinding.LoadConfig("../testdata/TestContextAddInheritance.toml", keybinding.WithRegistry(registry))
+	assert.Error(t, err, "Config should not load with bindings to unknown commands")
+	assert.Contains(t, err.Error(), "unknown command 'addToQueue'")
+	assert.Nil(t, config)
+}
+
+func TestLoadConfig_ExprBindings(t *testing.T) {
+	var favorited []string
+	env := map[string]interface{}{
+		"CurrentTrackID": "",
+		"favoriteTrack": func(trackID string) string {
+			favorited = append(favorited, trackID)
+			return trackID
+		},
+		"AddTrackToPlaylist": func(trackID string) string { return trackID },
+	}
+
+	config, err := keybinding.LoadConfig("../testdata/TestExprBindings.toml", keybinding.WithExprEnv(env))
+	require.NoError(t, err, "Config should load without error")
+
+	defaultBindings := (*config)["Default"].Bindings
+	assert.True(t, defaultBindings["y"].IsExpr())
+	assert.Equal(t, "favoriteTrack(CurrentTrackID)", defaultBindings["y"].Expr)
+	assert.Equal(t, "deleteTrack", defaultBindings["d"].Command)
+	assert.Equal(t, "queue.deleteTrack", (*config)["Queue"].Bindings["x"].Command, "Tables can hold commands too")
+	assert.True(t, (*config)["Queue"].Bindings["y"].IsExpr(), "Expression bindings should be inherited")
+
+	registry := command.NewRegistry()
+	registry.SetExprEnv(func(e *types.Event) interface{} {
+		env["CurrentTrackID"] = "12345"
+		return env
+	})
+
+	event := types.FromEventKey(tcell.NewEventKey(tcell.KeyRune, 'y', tcell.ModNone), config)
+	require.NoError(t, event.LookupCommand("Queue"))
+	assert.True(t, event.IsBound)
+	assert.Empty(t, event.Command, "Expression bindings have no command")
+	assert.NoError(t, registry.Dispatch(event))
+	assert.Equal(t, []string{"12345"}, favorited)
+}
+
+func TestLoadConfig_RichBindings(t *testing.T) {
+	var calls []*command.Call
+	handler := func(call *command.Call) error {
+		calls = append(calls, call)
+		return nil
+	}
+	registry := command.NewRegistry()
+	registry.MustRegister(command.Command{Name: "goToTop", Handler: handler})
+	registry.MustRegister(command.Command{Name: "queue.deleteTrack", Handler: handler})
+	registry.MustRegister(command.Command{Name: "search", Handler: handler, Args: []command.ArgSpec{
+		{Name: "query", Type: command.ArgString},
+		{Name: "limit", Type: command.ArgInt},
+	}})
+
+	config, err := keybinding.LoadConfig("../testdata/TestRichBindings.toml", keybinding.WithRegistry(registry))
+	require.NoError(t, err, "Config should load without error")
+
+	g := (*config)["Queue"].Bindings["g"]
+	assert.Equal(t, "Go to the first item", g.Description, "Metadata should be inherited")
+	assert.Equal(t, "Navigation", g.Category)
+
+	event := types.FromEventKey(tcell.NewEventKey(tcell.KeyRune, 'd', tcell.ModNone), config)
+	require.NoError(t, event.LookupCommand("Queue"))
+	assert.Equal(t, "Remove track", event.Binding.Description, "Metadata should be on the event")
+	assert.Equal(t, "Queue", event.Binding.Category)
+
+	event = types.FromEventKey(tcell.NewEventKey(tcell.KeyRune, '/', tcell.ModNone), config)
+	require.NoError(t, event.LookupCommand("Queue"))
+	require.NoError(t, registry.Dispatch(event))
+	require.Len(t, calls, 1)
+	assert.Equal(t, []interface{}{"artist: Foo", 10}, calls[0].Args, "Args should be passed to the command")
+}
+
+func TestLoadConfig_ExprCompileErrors(t *testing.T) {
+	env := map[string]interface{}{
+		"CurrentTrackID": "",
+		"favoriteTrack":  func(trackID string) string { return trackID },
+	}
+
+	var messages []string
+	log.SetLogHandler(func(message string) {
+		messages = append(messages, message)
+	})
+	defer log.SetLogHandler(nil)
+
+	config, err := keybinding.LoadConfig("../testdata/TestExprBindingsInvalid.toml", keybinding.WithExprEnv(env))
+	assert.Error(t, err, "Config should not load with broken expressions")
+	assert.Nil(t, config)
+	assert.Contains(t, err.Error(), "context 'Default': key 'y': expr 'favoriteTrack(CurrentTrackID'")
+
+	var reported []string
+	for _, message := range messages {
+		if strings.Contains(message, "Error: ") {
+			reported = append(reported, message)
+		}
+	}
+	if assert.Len(t, reported, 2, "Every broken expression should be reported") {
+		assert.Contains(t, reported[1], "context 'Default': key 'z': expr 'unknownFunction()'")
+	}
+}
+
+func TestLoadConfig_InvalidBindingTable(t *testing.T) {
+	config, err := keybinding.LoadConfig("../testdata/TestInvalidBindingTable.toml")
+	assert.Error(t, err, "A binding can't have both a command and an expression")
+	assert.Contains(t, err.Error(), "binding needs either 'command' or 'expr'")
+	assert.Nil(t, config)
+}
+
+func TestLoadConfig_WhenBindings(t *testing.T) {
+	state := map[string]interface{}{"TrackSelected": false, "Playing": false}
+
+	config, err := keybinding.LoadConfig("../testdata/TestWhenBindings.toml", keybinding.WithState(state))
+	require.NoError(t, err, "Config should load without error")
+
+	event := types.FromEventKey(tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModNone), config)
+	event.State = map[string]interface{}{"TrackSelected": true, "Playing": false}
+	assert.NoError(t, event.LookupCommand("Default"))
+	assert.Equal(t, "togglePlay", event.Command)
+
+	event.State = state
+	assert.NoError(t, event.LookupCommand("Default"))
+	assert.Equal(t, "openCommandPalette", event.Command)
+
+	config, err = keybinding.LoadConfig("../testdata/TestWhenBindingsInvalid.toml", keybinding.WithState(state))
+	assert.Error(t, err, "Conditions should be checked against the state")
+	assert.Contains(t, err.Error(), "context 'Default': key 'SPC': when 'TrackSeleted'")
+	assert.Nil(t, config)
 }
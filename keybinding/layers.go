@@ -0,0 +1,199 @@
+package keybinding
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/spezifisch/tview-command/types"
+)
+
+// Source is a config file to load, see LoadLayers.
+type Source struct {
+	// Name of the layer, e.g. "user". Bindings remember it in Layer.
+	Name string
+	// Path of the file, in FS if that is set. Errors name it as the file.
+	Path string
+	// FS to read the file from instead of the file system, e.g. an
+	// embed.FS with the app's default config.
+	FS fs.FS
+	// Data is the content of the file, if it is already loaded. Path is
+	// only its name then.
+	Data []byte
+	// Format of the file. If it is empty, the format of WithFormat is used,
+	// or the one of the extension of Path, see FormatOf.
+	Format Format
+	// Optional sources are skipped if the file doesn't exist.
+	Optional bool
+}
+
+func (s Source) read() ([]byte, error) {
+	if s.Data != nil {
+		return s.Data, nil
+	}
+	if s.FS != nil {
+		return fs.ReadFile(s.FS, s.Path)
+	}
+	return os.ReadFile(s.Path)
+}
+
+// DefaultSources returns the usual layers of an app's keybindings, from
+// lowest to highest priority:
+//
+//   - "default": fileName in defaults, e.g. the app's embedded keymap
+//   - "system": /etc/<app>/<fileName>
+//   - "user": <app>/<fileName> in the XDG config dir, ~/.config by default
+//   - "project": .<app>/<fileName> in the working directory
+//
+// Only the default layer must exist. It is left out if defaults is nil.
+func DefaultSources(app string, defaults fs.FS, fileName string) []Source {
+	var sources []Source
+	if defaults != nil {
+		sources = append(sources, Source{Name: "default", Path: fileName, FS: defaults})
+	}
+	sources = append(sources, Source{Name: "system", Path: filepath.Join("/etc", app, fileName), Optional: true})
+	if configDir, err := os.UserConfigDir(); err == nil {
+		sources = append(sources, Source{Name: "user", Path: filepath.Join(configDir, app, fileName), Optional: true})
+	}
+	sources = append(sources, Source{Name: "project", Path: filepath.Join("."+app, fileName), Optional: true})
+	return sources
+}
+
+// LoadLayers loads several config files and merges them into one config,
+// later sources override earlier ones:
+//
+//   - contexts are merged by name
+//   - bindings are merged key by key, a key unbound with false or "nop"
+//     stays unbound
+//   - settings are merged by name
+//   - context_add and context_override replace the lists of earlier layers
+//     if they are set, an empty list clears them
+//   - context_remove_keys are added to the ones of earlier layers
+//
+// Inheritance is resolved once for the merged config, so a user layer that
+// changes a preset changes it for every context that inherits it. Every
+// binding remembers the Name of its source in Binding.Layer.
+//
+// Included files are merged below the file that includes them, as part of
+// its layer, see LoadConfig.
+//
+// Like LoadConfig, problems in any of the files are returned together as
+// ConfigErrors.
+func LoadLayers(sources []Source, opts ...Option) (*types.Config, error) {
+	config, _, err := loadLayers(sources, newOptions(opts))
+	return config, err
+}
+
+// LoadRawLayers loads and merges sources like LoadLayers, but doesn't
+// resolve inheritance. Each context has only its own bindings, with
+// context_add and context_override kept, like in the files. Use it to change
+// a config and write it back, see SaveConfig.
+func LoadRawLayers(sources []Source, opts ...Option) (types.Config, error) {
+	config, _, err := mergeLayers(sources, newOptions(opts))
+	return config, err
+}
+
+// loadLayers is LoadLayers, it also returns the files that were read,
+// including the included ones.
+func loadLayers(sources []Source, o *options) (*types.Config, []Source, error) {
+	merged, files, err := mergeLayers(sources, o)
+	if err != nil {
+		return nil, files, err
+	}
+	config, err := resolveConfig(merged)
+	return config, files, err
+}
+
+// mergeLayers loads and merges sources and checks the inheritance graph of
+// the result.
+func mergeLayers(sources []Source, o *options) (types.Config, []Source, error) {
+	var files []Source
+	var errs ConfigErrors
+	merged := make(types.Config)
+	mergedPositions := make(positions)
+	for _, source := range sources {
+		layer, layerErrs := loadLayer(source, o, nil)
+		errs = append(errs, layerErrs...)
+		if layer == nil {
+			files = append(files, source)
+			continue
+		}
+		files = append(files, layer.files...)
+		mergeConfig(merged, layer.config)
+		mergedPositions.merge(layer.positions)
+	}
+
+	// The inheritance graph can only be checked for the merged config, the
+	// errors point to the layer that defines a context last
+	graphErrs, _ := DetectCycleAndValidate(merged).(ConfigErrors)
+	graphErrs.locate("", mergedPositions)
+	errs = append(errs, graphErrs...)
+	if len(errs) > 0 {
+		return nil, files, errs
+	}
+	return merged, files, nil
+}
+
+// layer is a decoded config file with normalized keys, merged with the files
+// it includes.
+type layer struct {
+	config    types.Config
+	positions positions
+	files     []Source // the file and the ones it includes
+}
+
+// mergeConfig merges the contexts of layer into config, see LoadLayers.
+func mergeConfig(config, layer types.Config) {
+	for contextName, context := range layer {
+		base, exists := config[contextName]
+		if !exists {
+			config[contextName] = context
+			continue
+		}
+
+		if context.ContextAdd != nil {
+			base.ContextAdd = context.ContextAdd
+		}
+		if context.ContextOverride != nil {
+			base.ContextOverride = context.ContextOverride
+		}
+		base.ContextRemoveKeys = append(append([]string(nil), base.ContextRemoveKeys...), context.ContextRemoveKeys...)
+
+		if len(context.Settings) > 0 {
+			settings := make(map[string]interface{}, len(base.Settings)+len(context.Settings))
+			for name, value := range base.Settings {
+				settings[name] = value
+			}
+			for name, value := range context.Settings {
+				settings[name] = value
+			}
+			base.Settings = settings
+		}
+
+		if len(context.Bindings) > 0 {
+			bindings := make(map[string]types.Binding, len(base.Bindings)+len(context.Bindings))
+			for keyName, binding := range base.Bindings {
+				bindings[keyName] = binding
+			}
+			for keyName, binding := range context.Bindings {
+				bindings[keyName] = binding
+			}
+			base.Bindings = bindings
+		}
+
+		config[contextName] = base
+	}
+}
+
+// withLayer returns the binding and its alternatives with Layer set.
+func withLayer(binding types.Binding, name string) types.Binding {
+	binding.Layer = name
+	if binding.Alternatives != nil {
+		alternatives := make([]types.Binding, len(binding.Alternatives))
+		for i, alternative := range binding.Alternatives {
+			alternatives[i] = withLayer(alternative, name)
+		}
+		binding.Alternatives = alternatives
+	}
+	return binding
+}
@@ -0,0 +1,119 @@
+package keybinding_test
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/spezifisch/tview-command/keybinding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var defaultLayer = fstest.MapFS{
+	"keys.toml": {Data: []byte(`
+[Default.bindings]
+q = "quit"
+x = "delete"
+
+[ListPreset.bindings]
+g = "list.first"
+G = "list.last"
+
+[Queue]
+context_add = ["ListPreset"]
+[Queue.bindings]
+"Ctrl+D" = "queue.deleteTrack"
+[Queue.settings]
+wrap = true
+title = "Queue"
+
+[Playlist]
+context_add = ["ListPreset"]
+`)},
+}
+
+func TestLoadLayers(t *testing.T) {
+	config, err := keybinding.LoadLayers([]keybinding.Source{
+		{Name: "default", Path: "keys.toml", FS: defaultLayer},
+		{Name: "system", Path: "../testdata/TestLoadLayers/missing.toml", Optional: true},
+		{Name: "user", Path: "../testdata/TestLoadLayers/user.toml"},
+	})
+	require.NoError(t, err, "Layers should load without error")
+
+	playlist := (*config)["Playlist"]
+	assert.Equal(t, "list.top", playlist.Bindings["g"].Command, "The user layer should change the preset for every context")
+	assert.Equal(t, "user", playlist.Bindings["g"].Layer)
+	assert.Equal(t, "list.last", playlist.Bindings["G"].Command, "Bindings the user doesn't change should stay")
+	assert.Equal(t, "default", playlist.Bindings["G"].Layer)
+
+	queue := (*config)["Queue"]
+	assert.Equal(t, "queue.clear", queue.Bindings["Ctrl+D"].Command, "Keys should be merged by their normalized name")
+	assert.Equal(t, "user", queue.Bindings["Ctrl+D"].Layer)
+	assert.NotContains(t, queue.Bindings, "g", "An empty context_add should clear the one of the default layer")
+	assert.True(t, queue.Bindings["x"].Unbind, "context_remove_keys of the user layer should apply")
+	assert.Equal(t, "quit", queue.Bindings["q"].Command)
+	assert.Equal(t, map[string]interface{}{"wrap": false, "title": "Queue"}, queue.Settings, "Settings should be merged by name")
+}
+
+func TestLoadLayers_Errors(t *testing.T) {
+	userPath := "../testdata/TestLoadLayers/user.toml"
+	defaults := fstest.MapFS{"keys.toml": {Data: []byte(`
+[ListPreset.bindings]
+"CTRL-9" = "list.first"
+
+[Queue.bindings]
+a = "add"
+`)}}
+
+	_, err := keybinding.LoadLayers([]keybinding.Source{
+		{Name: "default", Path: "keys.toml", FS: defaults},
+		{Name: "user", Path: userPath},
+	})
+	var errs keybinding.ConfigErrors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 1, "Only the invalid key should be reported")
+	assert.Equal(t, keybinding.KindInvalidKey, errs[0].Kind)
+	assert.Equal(t, keybinding.Position{File: "keys.toml", Line: 3, Column: 1}, errs[0].Position, "The error should point to the layer it is in")
+
+	project := fstest.MapFS{"project.toml": {Data: []byte(`
+[Search]
+context_add = ["ListPreset"]
+`)}}
+	_, err = keybinding.LoadLayers([]keybinding.Source{
+		{Name: "default", Path: "keys.toml", FS: defaultLayer},
+		{Name: "project", Path: "project.toml", FS: project},
+	})
+	assert.NoError(t, err, "A layer should be able to inherit from the contexts of another one")
+
+	_, err = keybinding.LoadLayers([]keybinding.Source{
+		{Name: "project", Path: "project.toml", FS: project},
+	})
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 1)
+	assert.Equal(t, keybinding.KindUnknownContext, errs[0].Kind)
+	assert.Equal(t, keybinding.Position{File: "project.toml", Line: 3, Column: 1}, errs[0].Position)
+
+	_, err = keybinding.LoadLayers([]keybinding.Source{
+		{Name: "user", Path: "../testdata/TestLoadLayers/missing.toml"},
+	})
+	assert.True(t, errors.Is(err, fs.ErrNotExist), "A missing source that isn't optional should be an error")
+}
+
+func TestDefaultSources(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/home/user/.config")
+
+	sources := keybinding.DefaultSources("player", defaultLayer, "keys.toml")
+	require.Len(t, sources, 4)
+
+	names := make([]string, len(sources))
+	for i, source := range sources {
+		names[i] = source.Name
+	}
+	assert.Equal(t, []string{"default", "system", "user", "project"}, names, "Sources should be ordered by priority")
+	assert.Equal(t, filepath.Join("/home/user/.config", "player", "keys.toml"), sources[2].Path)
+	assert.False(t, sources[0].Optional, "The default layer should be required")
+	assert.True(t, sources[3].Optional)
+}
@@ -0,0 +1,57 @@
+package keybinding
+
+import "github.com/spezifisch/tview-command/command"
+
+// Option changes how LoadConfig loads a config.
+type Option func(*options)
+
+type options struct {
+	registry *command.Registry
+	exprEnv  interface{}
+	state    map[string]interface{}
+	strict   bool
+	format   Format
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithRegistry makes LoadConfig check that every binding refers to a command
+// in registry, with valid arguments.
+func WithRegistry(registry *command.Registry) Option {
+	return func(o *options) {
+		o.registry = registry
+	}
+}
+
+// WithExprEnv makes LoadConfig compile expression bindings against the type
+// of env, so that unknown functions and variables or type errors are found
+// at load time. env is usually the zero value of the struct or a map like
+// the one the app passes to Registry.SetExprEnv.
+func WithExprEnv(env interface{}) Option {
+	return func(o *options) {
+		o.exprEnv = env
+	}
+}
+
+// WithState makes LoadConfig check the "when" conditions of bindings against
+// the entries of state. Pass the map the app later sets as Event.State, the
+// values are only used for their types.
+func WithState(state map[string]interface{}) Option {
+	return func(o *options) {
+		o.state = state
+	}
+}
+
+// WithStrict makes LoadConfig fail on unknown fields in contexts, like a
+// misspelled "contxt_add". Without it they are only logged as warnings.
+func WithStrict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
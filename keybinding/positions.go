@@ -0,0 +1,160 @@
+package keybinding
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// positions maps TOML key paths to where they are defined in a file. The
+// decoder doesn't tell, so the file is scanned for table headers and keys.
+// Keys of inline tables and keys inside of multi-line values aren't found.
+type positions map[string]Position
+
+// pathSeparator joins the parts of a key path, parts can contain dots.
+const pathSeparator = "\x1f"
+
+// indexPositions scans source, the content of file, for the table headers
+// and keys it defines.
+func indexPositions(file, source string) positions {
+	index := make(positions)
+	var table []string
+	multiline := ""
+
+	for i, line := range strings.Split(source, "\n") {
+		lineNumber := i + 1
+
+		// skip the content of multi-line strings
+		if multiline != "" {
+			if strings.Count(line, multiline)%2 == 1 {
+				multiline = ""
+			}
+			continue
+		}
+
+		trimmed := strings.TrimLeft(line, " \t")
+		offset := len(line) - len(trimmed)
+		if trimmed == "" || trimmed[0] == '#' {
+			continue
+		}
+
+		if trimmed[0] == '[' {
+			header := strings.TrimLeft(trimmed, "[")
+			headerOffset := offset + len(trimmed) - len(header)
+			keys, rest, ok := parseKeyPath(header)
+			if !ok || !strings.HasPrefix(strings.TrimLeft(rest, " \t"), "]") {
+				continue
+			}
+			table = keys
+			index.add(table, file, lineNumber, line, headerOffset+len(header)-len(strings.TrimLeft(header, " \t")))
+			continue
+		}
+
+		keys, rest, ok := parseKeyPath(trimmed)
+		if !ok || !strings.HasPrefix(strings.TrimLeft(rest, " \t"), "=") {
+			continue
+		}
+		path := append(append([]string(nil), table...), keys...)
+		index.add(path, file, lineNumber, line, offset)
+
+		value := strings.TrimLeft(strings.TrimLeft(rest, " \t")[1:], " \t")
+		for _, quotes := range []string{`"""`, `'''`} {
+			if strings.HasPrefix(value, quotes) && strings.Count(value, quotes)%2 == 1 {
+				multiline = quotes
+			}
+		}
+	}
+	return index
+}
+
+// add records the position of path, unless it is defined already. offset is
+// the byte offset of the path in line.
+func (index positions) add(path []string, file string, lineNumber int, line string, offset int) {
+	index.addPosition(path, Position{File: file, Line: lineNumber, Column: utf8.RuneCountInString(line[:offset]) + 1})
+}
+
+// addPosition records position for path, unless it is defined already.
+func (index positions) addPosition(path []string, position Position) {
+	key := strings.Join(path, pathSeparator)
+	if _, exists := index[key]; !exists {
+		index[key] = position
+	}
+}
+
+// merge adds the positions of other, they replace the ones of index.
+func (index positions) merge(other positions) {
+	for key, position := range other {
+		index[key] = position
+	}
+}
+
+// lookup returns the position of path, or of the closest table around it
+// that was found.
+func (index positions) lookup(path []string) Position {
+	for ; len(path) > 0; path = path[:len(path)-1] {
+		if position, ok := index[strings.Join(path, pathSeparator)]; ok {
+			return position
+		}
+	}
+	return Position{}
+}
+
+// parseKeyPath parses a dotted TOML key like `Queue.bindings` or
+// `"Ctrl+C"` at the start of s and returns its parts and the rest of s.
+func parseKeyPath(s string) ([]string, string, bool) {
+	var keys []string
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return nil, "", false
+		}
+
+		var key string
+		switch s[0] {
+		case '"', '\'':
+			end := closingQuote(s)
+			if end < 0 {
+				return nil, "", false
+			}
+			key = s[1:end]
+			if s[0] == '"' {
+				key = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(key)
+			}
+			s = s[end+1:]
+		default:
+			end := 0
+			for end < len(s) && isBareKeyChar(s[end]) {
+				end++
+			}
+			if end == 0 {
+				return nil, "", false
+			}
+			key = s[:end]
+			s = s[end:]
+		}
+		keys = append(keys, key)
+
+		rest := strings.TrimLeft(s, " \t")
+		if !strings.HasPrefix(rest, ".") {
+			return keys, s, true
+		}
+		s = rest[1:]
+	}
+}
+
+// closingQuote returns the index of the quote that ends the quoted key at
+// the start of s, or -1.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[0] == '"' && s[i] == '\\':
+			i++
+		case s[i] == s[0]:
+			return i
+		}
+	}
+	return -1
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
@@ -0,0 +1,150 @@
+package keybinding
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/spezifisch/tview-command/types"
+)
+
+// SaveConfig writes config to path as TOML, see types.Config.Encode. The
+// file is replaced at once, so a Watcher never reads half of it. If path is
+// a symlink, the file it points to is replaced.
+//
+// To keep a user's file small, save only what differs from the app's
+// defaults:
+//
+//	SaveConfig(path, DiffConfig(defaults, changed))
+func SaveConfig(path string, config types.Config) error {
+	var buf bytes.Buffer
+	if err := config.Encode(&buf); err != nil {
+		return err
+	}
+
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Chmod(mode); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// DiffConfig returns the layer that gives config when LoadLayers merges it
+// over base. Both are unresolved configs, see LoadRawLayers:
+//
+//   - contexts that aren't in base are kept as they are
+//   - bindings that are new or changed are kept
+//   - settings that are new or changed are kept
+//   - context_add and context_override are kept if they changed
+//   - context_remove_keys that aren't in base are kept
+//
+// Contexts, bindings and settings that config doesn't have can't be removed
+// by a layer, they stay as they are in base. To take a key away, unbind it
+// with Binding.Unbind.
+func DiffConfig(base, config types.Config) types.Config {
+	delta := make(types.Config)
+	for contextName, context := range config {
+		baseContext, exists := base[contextName]
+		if !exists {
+			delta[contextName] = context
+			continue
+		}
+
+		var diff types.Context
+		changed := false
+		if !sameNames(context.ContextAdd, baseContext.ContextAdd) {
+			diff.ContextAdd = append(types.ContextNames{}, context.ContextAdd...)
+			changed = true
+		}
+		if !sameNames(context.ContextOverride, baseContext.ContextOverride) {
+			diff.ContextOverride = append(types.ContextNames{}, context.ContextOverride...)
+			changed = true
+		}
+		for _, keyName := range context.ContextRemoveKeys {
+			if !contains(baseContext.ContextRemoveKeys, keyName) {
+				diff.ContextRemoveKeys = append(diff.ContextRemoveKeys, keyName)
+				changed = true
+			}
+		}
+
+		for name, value := range context.Settings {
+			if baseValue, exists := baseContext.Settings[name]; !exists || !reflect.DeepEqual(value, baseValue) {
+				if diff.Settings == nil {
+					diff.Settings = make(map[string]interface{})
+				}
+				diff.Settings[name] = value
+				changed = true
+			}
+		}
+
+		for keyName, binding := range context.Bindings {
+			if baseBinding, exists := baseContext.Bindings[keyName]; !exists || !sameBinding(binding, baseBinding) {
+				if diff.Bindings == nil {
+					diff.Bindings = make(map[string]types.Binding)
+				}
+				diff.Bindings[keyName] = binding
+				changed = true
+			}
+		}
+
+		if changed {
+			delta[contextName] = diff
+		}
+	}
+	return delta
+}
+
+// sameNames reports whether two lists of contexts are the same, a missing
+// list is the same as an empty one.
+func sameNames(a, b types.ContextNames) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sameBinding reports whether two bindings do the same and are described the
+// same way. Where they come from doesn't matter.
+func sameBinding(a, b types.Binding) bool {
+	if len(a.Alternatives) != len(b.Alternatives) || (a.Alternatives == nil) != (b.Alternatives == nil) {
+		return false
+	}
+	for i := range a.Alternatives {
+		if !sameBinding(a.Alternatives[i], b.Alternatives[i]) {
+			return false
+		}
+	}
+	return a.Command == b.Command && a.Expr == b.Expr && a.When == b.When && a.Unbind == b.Unbind &&
+		a.Description == b.Description && a.Category == b.Category && reflect.DeepEqual(a.Args, b.Args)
+}
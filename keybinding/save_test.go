@@ -0,0 +1,133 @@
+package keybinding_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spezifisch/tview-command/keybinding"
+	"github.com/spezifisch/tview-command/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode_RoundTrip(t *testing.T) {
+	for _, path := range []string{
+		"../testdata/TestProvenance.toml",
+		"../testdata/TestSubContexts.toml",
+		"../testdata/TestRichBindings.toml",
+		"../testdata/TestFormats.toml",
+		"../testdata/TestUnbindKeys.toml",
+		"../testdata/TestContextSkipInheritWithEmpty.toml",
+		"../testdata/TestKeySequences.toml",
+		"../testdata/TestInclude/main.toml",
+	} {
+		expected, err := keybinding.LoadConfig(path)
+		require.NoError(t, err, path)
+		raw, err := keybinding.LoadRawConfig(path)
+		require.NoError(t, err, path)
+
+		var buf bytes.Buffer
+		require.NoError(t, raw.Encode(&buf), path)
+
+		config, err := keybinding.LoadConfigFromBytes("saved.toml", buf.Bytes())
+		require.NoError(t, err, "%s should load after encoding:\n%s", path, buf.String())
+		assert.Equal(t, expected, config, "%s should resolve the same after encoding", path)
+
+		again, err := keybinding.LoadRawConfig(path)
+		require.NoError(t, err, path)
+		var second bytes.Buffer
+		require.NoError(t, again.Encode(&second))
+		assert.Equal(t, buf.String(), second.String(), "%s should encode the same every time", path)
+	}
+}
+
+func TestSaveConfig_Delta(t *testing.T) {
+	basePath := "../testdata/TestProvenance.toml"
+	base, err := keybinding.LoadRawConfig(basePath)
+	require.NoError(t, err)
+
+	// What a rebind screen does: change the user's copy of the config
+	changed, err := keybinding.LoadRawConfig(basePath)
+	require.NoError(t, err)
+	queue := changed["Queue"]
+	queue.Bindings["Ctrl+D"] = types.Binding{Command: "queue.clear", Description: "Clear the queue"}
+	queue.Bindings["d"] = types.Binding{Unbind: true}
+	queue.ContextAdd = nil
+	queue.Settings = map[string]interface{}{"wrap": true}
+	changed["Queue"] = queue
+	changed["Search"] = types.Context{ContextAdd: types.ContextNames{"ListPreset"}}
+
+	userPath := filepath.Join(t.TempDir(), "user.toml")
+	require.NoError(t, keybinding.SaveConfig(userPath, keybinding.DiffConfig(base, changed)))
+
+	saved, err := os.ReadFile(userPath)
+	require.NoError(t, err)
+	assert.Equal(t, `[Queue]
+context_add = []
+
+[Queue.settings]
+wrap = true
+
+[Queue.bindings]
+"Ctrl+D" = { command = "queue.clear", description = "Clear the queue" }
+d = false
+
+[Search]
+context_add = ["ListPreset"]
+`, string(saved), "Only the changes should be saved")
+
+	var expected bytes.Buffer
+	require.NoError(t, changed.Encode(&expected))
+	want, err := keybinding.LoadConfigFromBytes("changed.toml", expected.Bytes())
+	require.NoError(t, err)
+
+	config, err := keybinding.LoadLayers([]keybinding.Source{{Path: basePath}, {Path: userPath}})
+	require.NoError(t, err)
+	assert.Equal(t, want, config, "The saved delta over the base should give the changed config")
+}
+
+func TestSaveConfig_Replace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	require.NoError(t, os.WriteFile(path, []byte("[Default.bindings]\nq = \"quit\"\n"), 0o600))
+
+	require.NoError(t, keybinding.SaveConfig(path, types.Config{"Default": {Bindings: map[string]types.Binding{"q": {Command: "exit"}}}}))
+
+	config, err := keybinding.LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, "exit", (*config)["Default"].Bindings["q"].Command)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "The mode of the file should be kept")
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "No temporary files should be left")
+}
+
+func TestSaveConfig_Symlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles", "keys.toml")
+	require.NoError(t, os.Mkdir(filepath.Dir(target), 0o755))
+	require.NoError(t, os.WriteFile(target, []byte("[Default.bindings]\nq = \"quit\"\n"), 0o644))
+	link := filepath.Join(dir, "config.toml")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	require.NoError(t, keybinding.SaveConfig(link, types.Config{"Default": {Bindings: map[string]types.Binding{"q": {Command: "exit"}}}}))
+
+	info, err := os.Lstat(link)
+	require.NoError(t, err)
+	assert.Equal(t, os.ModeSymlink, info.Mode()&os.ModeSymlink, "The symlink should be kept")
+
+	config, err := keybinding.LoadConfig(target)
+	require.NoError(t, err)
+	assert.Equal(t, "exit", (*config)["Default"].Bindings["q"].Command, "The file the symlink points to should be replaced")
+
+	entries, err := os.ReadDir(filepath.Dir(target))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "The temporary file should be next to the target")
+}
@@ -1,11 +1,310 @@
 package keybinding
 
-import "github.com/spezifisch/tview-command/types"
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/expr-lang/expr"
+
+	"github.com/spezifisch/tview-command/log"
+	"github.com/spezifisch/tview-command/types"
+)
+
+// ValidateConfig runs all checks that don't depend on the app on config. The
+// result is ConfigErrors with the problems of every check.
 func ValidateConfig(config types.Config) error {
-	// space for other checks in the future
+	var errs ConfigErrors
+	errs.add(KindInvalidKey, ValidateKeys(config))
+	errs.add(KindInvalidSetting, ValidateSettings(config))
+	errs.add(KindInvalidCommand, ValidateCommands(config))
+	errs.add(KindCycle, DetectCycleAndValidate(config))
+	return errs.err()
+}
+
+// InvalidKeyError describes a binding whose key can never be pressed.
+type InvalidKeyError struct {
+	Context    string
+	Key        string
+	Reason     error
+	Suggestion string
+}
+
+func (e *InvalidKeyError) Error() string {
+	msg := fmt.Sprintf("context '%s': key '%s' %v", e.Context, e.Key, e.Reason)
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf(" (did you mean '%s'?)", e.Suggestion)
+	}
+	return msg
+}
+
+// ValidateKeys checks the key names of all bindings in config, and of the
+// context_remove_keys lists.
+//
+// Keys that are spelled differently but are the same key, like "Ctrl-C" and
+// "Ctrl+C", are errors, see DuplicateKeyError.
+//
+// Names that parse but that tcell can never deliver, like "CTRL-9", are
+// errors. Names that don't parse at all only cause a warning, because they
+// are also used as free-form identifiers by some apps. Every problem is
+// logged, the errors are returned as ConfigErrors.
+func ValidateKeys(config types.Config) error {
+	var errs ConfigErrors
+	for _, contextName := range sortedContextNames(config) {
+		bindings := config[contextName].Bindings
+		for _, keyName := range sortedKeyNames(bindings) {
+			keys, err := types.ParseKeySequence(keyName)
+			if err != nil {
+				warning := &InvalidKeyError{
+					Context:    contextName,
+					Key:        keyName,
+					Reason:     fmt.Errorf("is not a known key name"),
+					Suggestion: types.SuggestKey(keyName),
+				}
+				log.LogMessage("Warning: " + warning.Error())
+				continue
+			}
+
+			if err := validateSequence(keys); err != nil {
+				err.Context = contextName
+				err.Key = keyName
+				log.LogMessage("Error: " + err.Error())
+				errs = append(errs, &ConfigError{Kind: KindInvalidKey, Context: contextName, Key: keyName, Err: err})
+			}
+		}
+
+		for _, duplicate := range duplicateKeys(contextName, bindings) {
+			log.LogMessage("Error: " + duplicate.Error())
+			errs = append(errs, &ConfigError{Kind: KindDuplicateKey, Context: contextName, Key: duplicate.Second, Err: duplicate})
+		}
+
+		for _, keyName := range config[contextName].ContextRemoveKeys {
+			if _, err := types.ParseKeyRange(keyName); err == nil {
+				continue
+			}
+			if _, err := types.ParseKeySequence(keyName); err != nil {
+				warning := &InvalidKeyError{
+					Context:    contextName,
+					Key:        keyName,
+					Reason:     fmt.Errorf("in 'context_remove_keys' is neither a known key name nor a range like '[a-z]'"),
+					Suggestion: types.SuggestKey(keyName),
+				}
+				log.LogMessage("Warning: " + warning.Error())
+			}
+		}
+	}
+	return errs.err()
+}
+
+// DuplicateKeyError describes two spellings of the same key in a context,
+// like "Ctrl-C" and "ctrl+c". Only one of them could be used.
+type DuplicateKeyError struct {
+	Context string
+	Key     string // the canonical name
+	First   string
+	Second  string
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("context '%s': keys '%s' and '%s' are both '%s'", e.Context, e.First, e.Second, e.Key)
+}
+
+// duplicateKeys returns the keys of bindings that normalize to the same key
+// as another one, in a stable order.
+func duplicateKeys(contextName string, bindings map[string]types.Binding) []*DuplicateKeyError {
+	var duplicates []*DuplicateKeyError
+	spellings := make(map[string]string, len(bindings))
+	for _, keyName := range sortedKeyNames(bindings) {
+		normalized := types.CanonicalKeyName(keyName)
+		if first, exists := spellings[normalized]; exists {
+			duplicates = append(duplicates, &DuplicateKeyError{Context: contextName, Key: normalized, First: first, Second: keyName})
+			continue
+		}
+		spellings[normalized] = keyName
+	}
+	return duplicates
+}
 
-	// TODO check if key or key combinations of entries are valid
+// validateSequence checks that every key of a sequence can be pressed.
+// The suggestion of the returned error is the whole sequence with the bad
+// keys replaced.
+func validateSequence(keys []types.Key) *InvalidKeyError {
+	var invalid *InvalidKeyError
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key.String()
+		suggestion, err := key.Validate()
+		if err == nil {
+			continue
+		}
+		if invalid == nil {
+			invalid = &InvalidKeyError{Reason: fmt.Errorf("can never be pressed: %v", err)}
+		}
+		if suggestion == "" {
+			// nothing to offer for this key, so nothing for the sequence
+			names = nil
+			break
+		}
+		names[i] = suggestion
+	}
+	if invalid != nil && names != nil {
+		invalid.Suggestion = strings.Join(names, " ")
+	}
+	return invalid
+}
+
+// ValidateSettings checks the types of the context settings the library
+// understands itself, and that the keys in fallthrough_keys are valid.
+func ValidateSettings(config types.Config) error {
+	var errs ConfigErrors
+	for _, contextName := range sortedContextNames(config) {
+		settings := config[contextName].Settings
+		invalid := func(setting string, err error) {
+			errs = append(errs, &ConfigError{Kind: KindInvalidSetting, Context: contextName, Field: "settings", Key: setting, Err: err})
+		}
+
+		if opaque, exists := settings[types.SettingOpaque]; exists {
+			if _, ok := opaque.(bool); !ok {
+				invalid(types.SettingOpaque, fmt.Errorf("context '%s': setting '%s' must be true or false, got %v", contextName, types.SettingOpaque, opaque))
+			}
+		}
+
+		if timeout, exists := settings[types.SettingSequenceTimeout]; exists {
+			duration, ok := timeout.(string)
+			if parsed, err := time.ParseDuration(duration); !ok || err != nil || parsed < 0 {
+				invalid(types.SettingSequenceTimeout, fmt.Errorf("context '%s': setting '%s' must be a duration like \"500ms\", got %v", contextName, types.SettingSequenceTimeout, timeout))
+			}
+		}
+
+		entries, exists := settings[types.SettingFallthroughKeys]
+		if !exists {
+			continue
+		}
+		list, ok := entries.([]interface{})
+		if !ok {
+			invalid(types.SettingFallthroughKeys, fmt.Errorf("context '%s': setting '%s' must be a list of keys, got %v", contextName, types.SettingFallthroughKeys, entries))
+			continue
+		}
+		for _, entry := range list {
+			keyName, ok := entry.(string)
+			if !ok {
+				invalid(types.SettingFallthroughKeys, fmt.Errorf("context '%s': setting '%s' must be a list of keys, got %v", contextName, types.SettingFallthroughKeys, entry))
+				continue
+			}
+			keys, err := types.ParseKeySequence(keyName)
+			if err != nil {
+				invalid(types.SettingFallthroughKeys, &InvalidKeyError{
+					Context:    contextName,
+					Key:        keyName,
+					Reason:     fmt.Errorf("in '%s' is not a known key name", types.SettingFallthroughKeys),
+					Suggestion: types.SuggestKey(keyName),
+				})
+				continue
+			}
+			if invalidKey := validateSequence(keys); invalidKey != nil {
+				invalidKey.Context = contextName
+				invalidKey.Key = keyName
+				invalid(types.SettingFallthroughKeys, invalidKey)
+			}
+		}
+	}
+	return errs.err()
+}
+
+// ValidateCommands checks that every command binding is a valid command
+// chain with fitting args, see types.Binding.Commands. Expression bindings are checked by
+// CompileExpressions.
+func ValidateCommands(config types.Config) error {
+	var errs ConfigErrors
+	for _, contextName := range sortedContextNames(config) {
+		bindings := config[contextName].Bindings
+		for _, keyName := range sortedKeyNames(bindings) {
+			for _, binding := range bindings[keyName].Candidates() {
+				if binding.IsExpr() || binding.Unbind {
+					continue
+				}
+				if _, err := binding.Commands(); err != nil {
+					errs = append(errs, &ConfigError{
+						Kind:    KindInvalidCommand,
+						Context: contextName,
+						Key:     keyName,
+						Err:     fmt.Errorf("context '%s': key '%s': %w", contextName, keyName, err),
+					})
+					break
+				}
+			}
+		}
+	}
+	return errs.err()
+}
+
+// CompileExpressions compiles the expression bindings of config, against the
+// type of env if it isn't nil, and keeps the programs in the bindings. Every
+// binding that doesn't compile is logged, the errors are returned as
+// ConfigErrors.
+func CompileExpressions(config types.Config, env interface{}) error {
+	var opts []expr.Option
+	if env != nil {
+		opts = append(opts, expr.Env(env))
+	}
+
+	return compileBindings(config, KindInvalidExpression, func(binding *types.Binding) error {
+		return binding.Compile(opts...)
+	})
+}
+
+// CompileConditions compiles the "when" conditions of the bindings in config
+// like CompileExpressions does. If state isn't nil, conditions are checked
+// against it, so they can only use the state entries the app provides.
+func CompileConditions(config types.Config, state map[string]interface{}) error {
+	var opts []expr.Option
+	if state != nil {
+		opts = append(opts, expr.Env(state))
+	}
+
+	return compileBindings(config, KindInvalidCondition, func(binding *types.Binding) error {
+		return binding.CompileWhen(opts...)
+	})
+}
+
+// compileBindings calls compile for every binding of config and stores the
+// result. Errors are logged and returned as ConfigErrors of kind.
+func compileBindings(config types.Config, kind ErrorKind, compile func(binding *types.Binding) error) error {
+	var errs ConfigErrors
+	for _, contextName := range sortedContextNames(config) {
+		bindings := config[contextName].Bindings
+		for _, keyName := range sortedKeyNames(bindings) {
+			binding := bindings[keyName]
+			if err := compile(&binding); err != nil {
+				err = fmt.Errorf("context '%s': key '%s': %v", contextName, keyName, err)
+				log.LogMessage("Error: " + err.Error())
+				errs = append(errs, &ConfigError{Kind: kind, Context: contextName, Key: keyName, Err: err})
+				continue
+			}
+			bindings[keyName] = binding
+		}
+	}
+	return errs.err()
+}
+
+// sortedContextNames returns the context names of config in a stable order,
+// so that errors are reported deterministically.
+func sortedContextNames(config types.Config) []string {
+	names := make([]string, 0, len(config))
+	for name := range config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
 
-	return DetectCycleAndValidate(config)
+// sortedKeyNames returns the keys of bindings in a stable order.
+func sortedKeyNames(bindings map[string]types.Binding) []string {
+	keyNames := make([]string, 0, len(bindings))
+	for keyName := range bindings {
+		keyNames = append(keyNames, keyName)
+	}
+	sort.Strings(keyNames)
+	return keyNames
 }
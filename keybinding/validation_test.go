@@ -0,0 +1,148 @@
+package keybinding_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/spezifisch/tview-command/keybinding"
+	"github.com/spezifisch/tview-command/log"
+	"github.com/spezifisch/tview-command/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateKeys_Valid(t *testing.T) {
+	config := types.Config{
+		"Global":  {Bindings: map[string]types.Binding{"ESC": {Command: "closeModal"}, "CTRL-C": {Command: "copy"}}},
+		"Default": {Bindings: map[string]types.Binding{"d": {Command: "deleteTrack"}, "SPC": {Command: "openCommandPalette"}}},
+	}
+
+	assert.NoError(t, keybinding.ValidateKeys(config))
+}
+
+func TestValidateKeys_Unreachable(t *testing.T) {
+	config := types.Config{
+		"Global": {Bindings: map[string]types.Binding{"CTRL-9": {Command: "invalidKey"}}},
+	}
+
+	err := keybinding.ValidateKeys(config)
+	assert.Error(t, err, "Ctrl+9 can't be sent by a terminal")
+
+	var invalid *keybinding.InvalidKeyError
+	if assert.ErrorAs(t, err, &invalid) {
+		assert.Equal(t, "Global", invalid.Context)
+		assert.Equal(t, "CTRL-9", invalid.Key)
+		assert.Equal(t, "Alt+9", invalid.Suggestion)
+	}
+	assert.Contains(t, err.Error(), "did you mean 'Alt+9'?")
+}
+
+func TestValidateKeys_UnknownNameWarns(t *testing.T) {
+	var messages []string
+	log.SetLogHandler(func(msg string) {
+		messages = append(messages, msg)
+	})
+	defer log.SetLogHandler(nil)
+
+	config := types.Config{
+		"Default": {Bindings: map[string]types.Binding{"d?": {Command: "deleteTrack"}, "entr": {Command: "confirmAction"}}},
+	}
+
+	assert.NoError(t, keybinding.ValidateKeys(config), "Unknown key names should only warn")
+	output := strings.Join(messages, "\n")
+	assert.Contains(t, output, "context 'Default': key 'd?' is not a known key name (did you mean 'd'?)")
+	assert.Contains(t, output, "context 'Default': key 'entr' is not a known key name (did you mean 'Enter'?)")
+}
+
+func TestValidateKeys_UnreachableInSequence(t *testing.T) {
+	config := types.Config{
+		"Default": {Bindings: map[string]types.Binding{"SPC Ctrl+3 s": {Command: "badges.show"}}},
+	}
+
+	err := keybinding.ValidateKeys(config)
+	assert.Error(t, err, "Every key of a sequence must be reachable")
+	assert.Contains(t, err.Error(), "did you mean 'SPC ESC s'?")
+}
+
+func TestValidateSettings(t *testing.T) {
+	valid := types.Config{
+		"Modal": {Settings: map[string]interface{}{
+			"opaque":           true,
+			"fallthrough_keys": []interface{}{"Ctrl-C"},
+		}},
+	}
+	assert.NoError(t, keybinding.ValidateSettings(valid))
+
+	notBool := types.Config{
+		"Modal": {Settings: map[string]interface{}{"opaque": "yes"}},
+	}
+	assert.EqualError(t, keybinding.ValidateSettings(notBool), "context 'Modal': setting 'opaque' must be true or false, got yes")
+
+	notList := types.Config{
+		"Modal": {Settings: map[string]interface{}{"fallthrough_keys": "Ctrl-C"}},
+	}
+	assert.Error(t, keybinding.ValidateSettings(notList))
+
+	badKey := types.Config{
+		"Modal": {Settings: map[string]interface{}{"fallthrough_keys": []interface{}{"Ctrl-9"}}},
+	}
+	err := keybinding.ValidateSettings(badKey)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "context 'Modal': key 'Ctrl-9'")
+
+	timeout := types.Config{
+		"Global": {Settings: map[string]interface{}{"sequence_timeout": "500ms"}},
+	}
+	assert.NoError(t, keybinding.ValidateSettings(timeout))
+	for _, value := range []interface{}{"500", int64(500), "-1s"} {
+		timeout["Global"].Settings["sequence_timeout"] = value
+		assert.EqualError(t, keybinding.ValidateSettings(timeout),
+			fmt.Sprintf("context 'Global': setting 'sequence_timeout' must be a duration like \"500ms\", got %v", value))
+	}
+}
+
+func TestValidateCommands(t *testing.T) {
+	valid := types.Config{
+		"Browser": {Bindings: map[string]types.Binding{"a": {Command: "addArtistToQueue; cursorDown"}, "y": {Command: "favoriteTrack toggle"}}},
+	}
+	assert.NoError(t, keybinding.ValidateCommands(valid))
+
+	invalid := types.Config{
+		"Browser": {Bindings: map[string]types.Binding{"a": {Command: "addArtistToQueue & cursorDown"}}},
+	}
+	err := keybinding.ValidateCommands(invalid)
+	var syntaxErr *types.CommandSyntaxError
+	assert.ErrorAs(t, err, &syntaxErr)
+	assert.EqualError(t, err, "context 'Browser': key 'a': syntax error at column 18 of 'addArtistToQueue & cursorDown': unexpected '&', use '&&' to chain commands")
+}
+
+func TestValidateKeys_Duplicates(t *testing.T) {
+	config := types.Config{
+		"Global": {Bindings: map[string]types.Binding{
+			"Ctrl-C": {Command: "copy"},
+			"ctrl+c": {Command: "copyAll"},
+			"<C-c>":  {Command: "copyMore"},
+			"q":      {Command: "quit"},
+		}},
+	}
+
+	err := keybinding.ValidateKeys(config)
+	var errs keybinding.ConfigErrors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 2, "Every extra spelling should be reported")
+	assert.Equal(t, keybinding.KindDuplicateKey, errs[0].Kind)
+	assert.EqualError(t, errs[0].Err, "context 'Global': keys '<C-c>' and 'Ctrl-C' are both 'Ctrl+C'")
+	assert.EqualError(t, errs[1].Err, "context 'Global': keys '<C-c>' and 'ctrl+c' are both 'Ctrl+C'")
+}
+
+func TestLoadConfig_DuplicateKeys(t *testing.T) {
+	configPath := "../testdata/TestDuplicateKeys.toml"
+	for i := 0; i < 5; i++ {
+		_, err := keybinding.LoadConfig(configPath)
+		var duplicate *keybinding.DuplicateKeyError
+		require.ErrorAs(t, err, &duplicate)
+		assert.Equal(t, keybinding.DuplicateKeyError{Context: "Global", Key: "Ctrl+X Ctrl+S", First: "C-x C-s", Second: "Ctrl+X Ctrl+S"}, *duplicate, "The result should not depend on map order")
+		assert.Contains(t, err.Error(), configPath+":3:1: context 'Global': keys 'Ctrl-C' and 'ctrl+c' are both 'Ctrl+C'")
+	}
+}
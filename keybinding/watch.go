@@ -0,0 +1,294 @@
+package keybinding
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spezifisch/tview-command/log"
+	"github.com/spezifisch/tview-command/types"
+)
+
+// DefaultPollInterval is how often a Watcher checks its files by default.
+const DefaultPollInterval = time.Second
+
+// Watcher keeps a config up to date with its files while the app runs. It
+// polls the files, including the ones they include, and reloads all of them
+// when one changes. The new config only replaces the current one if it loads
+// without errors, otherwise the errors are passed to the error handler and
+// the old config stays.
+//
+// Configs are never changed once Config returned them, a reload builds a new
+// one and swaps it in atomically. Code that still holds the old config keeps
+// seeing it unchanged.
+type Watcher struct {
+	sources  []Source
+	opts     []Option
+	interval time.Duration
+	onReload func(*types.Config)
+	onError  func(error)
+
+	config atomic.Pointer[types.Config]
+
+	mu     sync.Mutex // serializes reloads
+	files  []Source   // the sources and the files they include
+	stamps []fileStamp
+
+	handlerMu  sync.Mutex // serializes the calls of onReload
+	delivering bool
+	delivered  *types.Config // the config onReload was last called with
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// WatchOption changes how a Watcher works.
+type WatchOption func(*Watcher)
+
+// WithLoadOptions passes opts to LoadLayers on every load.
+func WithLoadOptions(opts ...Option) WatchOption {
+	return func(w *Watcher) {
+		w.opts = append(w.opts, opts...)
+	}
+}
+
+// WithPollInterval sets how often the files are checked, DefaultPollInterval
+// if not set.
+func WithPollInterval(interval time.Duration) WatchOption {
+	return func(w *Watcher) {
+		w.interval = interval
+	}
+}
+
+// OnReload sets a function that is called with the new config after
+// successful reloads. It runs on the goroutine of the Watcher, or of the
+// caller of Reload, apps with a UI usually hand the config to their event
+// loop, e.g. with tview.Application.QueueUpdate. It may call Reload itself.
+//
+// Calls never overlap and always get the current config, so the last call
+// has the config that Config returns. If configs are swapped in while the
+// handler runs, it is called once more with the newest one only.
+func OnReload(handler func(*types.Config)) WatchOption {
+	return func(w *Watcher) {
+		w.onReload = handler
+	}
+}
+
+// OnError sets a function that is called when a changed config doesn't load,
+// with the error of LoadLayers. Without it the errors are only logged.
+func OnError(handler func(error)) WatchOption {
+	return func(w *Watcher) {
+		w.onError = handler
+	}
+}
+
+// WatchConfig loads the config at path like LoadConfig and watches it for
+// changes, see Watcher.
+func WatchConfig(path string, opts ...WatchOption) (*Watcher, error) {
+	return WatchLayers([]Source{{Path: path}}, opts...)
+}
+
+// WatchLayers loads sources like LoadLayers and watches all of them for
+// changes, including optional ones that are created or removed later. If the
+// first load fails there is no Watcher and the error is returned. Close
+// stops watching.
+func WatchLayers(sources []Source, opts ...WatchOption) (*Watcher, error) {
+	w := &Watcher{
+		sources:  sources,
+		interval: DefaultPollInterval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	config, files, err := loadLayers(w.sources, newOptions(w.opts))
+	if err != nil {
+		return nil, err
+	}
+	w.files = files
+	w.stamps = stat(files)
+	w.config.Store(config)
+	w.delivered = config
+
+	go w.poll()
+	return w, nil
+}
+
+// Config returns the current config. It is safe to call from any goroutine.
+func (w *Watcher) Config() *types.Config {
+	return w.config.Load()
+}
+
+// Reload loads the config now, whether the files changed or not. On success
+// the new config is swapped in and passed to the reload handler, see
+// OnReload, on failure the error is returned and the old config stays. The
+// error handler isn't called.
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	w.stamps = stat(w.files)
+	err := w.load()
+	w.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	w.reloaded()
+	return nil
+}
+
+// Close stops watching the files and waits for a running reload to finish.
+func (w *Watcher) Close() {
+	w.closeOnce.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+}
+
+func (w *Watcher) poll() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+// check reloads the config if a file changed since the last load. A config
+// that doesn't load is only reported once, not on every poll.
+func (w *Watcher) check() {
+	w.mu.Lock()
+	stamps := stat(w.files)
+	if stampsEqual(stamps, w.stamps) {
+		w.mu.Unlock()
+		return
+	}
+	w.stamps = stamps
+
+	log.LogMessage("Config changed, reloading.")
+	err := w.load()
+	w.mu.Unlock()
+
+	if err != nil {
+		log.LogMessage("Error: config not reloaded: " + err.Error())
+		if w.onError != nil {
+			w.onError(err)
+		}
+		return
+	}
+	w.reloaded()
+}
+
+// load loads the sources and swaps in the config if it has no errors. If
+// the included files changed, they are watched from now on. The caller holds
+// w.mu.
+func (w *Watcher) load() error {
+	config, files, err := loadLayers(w.sources, newOptions(w.opts))
+	if !samePaths(files, w.files) {
+		w.files = files
+		w.stamps = stat(files)
+	}
+	if err != nil {
+		return err
+	}
+	w.config.Store(config)
+	return nil
+}
+
+// reloaded calls the reload handler with the current config until it has
+// seen the newest one. If a call is running already, on another goroutine or
+// further up when the handler called Reload, that one takes care of it. The
+// handler runs without the locks, so that it can call Reload.
+func (w *Watcher) reloaded() {
+	if w.onReload == nil {
+		return
+	}
+
+	w.handlerMu.Lock()
+	if w.delivering {
+		w.handlerMu.Unlock()
+		return
+	}
+	w.delivering = true
+	for {
+		config := w.config.Load()
+		if config == w.delivered {
+			w.delivering = false
+			w.handlerMu.Unlock()
+			return
+		}
+		w.delivered = config
+		w.handlerMu.Unlock()
+
+		w.onReload(config)
+
+		w.handlerMu.Lock()
+	}
+}
+
+// fileStamp is what a Watcher remembers about a file to notice changes.
+type fileStamp struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+// stat returns the stamps of files, in the same order.
+func stat(files []Source) []fileStamp {
+	stamps := make([]fileStamp, len(files))
+	for i, source := range files {
+		if source.Data != nil {
+			stamps[i] = fileStamp{exists: true}
+			continue
+		}
+		var info fs.FileInfo
+		var err error
+		if source.FS != nil {
+			info, err = fs.Stat(source.FS, source.Path)
+		} else {
+			info, err = os.Stat(source.Path)
+		}
+		if err != nil {
+			// A file that can't be read is a change once it can be again
+			stamps[i] = fileStamp{exists: !errors.Is(err, fs.ErrNotExist)}
+			continue
+		}
+		stamps[i] = fileStamp{exists: true, size: info.Size(), modTime: info.ModTime()}
+	}
+	return stamps
+}
+
+func samePaths(a, b []Source) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Path != b[i].Path {
+			return false
+		}
+	}
+	return true
+}
+
+func stampsEqual(a, b []fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].exists != b[i].exists || a[i].size != b[i].size || !a[i].modTime.Equal(b[i].modTime) {
+			return false
+		}
+	}
+	return true
+}
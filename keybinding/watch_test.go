@@ -0,0 +1,200 @@
+package keybinding_test
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spezifisch/tview-command/keybinding"
+	"github.com/spezifisch/tview-command/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeConfig writes content to path with a modification time that is
+// different from the last one, so that a Watcher notices the change.
+func writeConfig(t *testing.T, path, content string, version int) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	modTime := time.Now().Add(time.Duration(version) * time.Second)
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestWatchConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	writeConfig(t, path, "[Default.bindings]\nq = \"quit\"\n", 0)
+
+	reloaded := make(chan *types.Config, 1)
+	failed := make(chan error, 1)
+	watcher, err := keybinding.WatchConfig(path,
+		keybinding.WithPollInterval(10*time.Millisecond),
+		keybinding.OnReload(func(config *types.Config) { reloaded <- config }),
+		keybinding.OnError(func(err error) { failed <- err }),
+	)
+	require.NoError(t, err, "Config should load without error")
+	defer watcher.Close()
+
+	old := watcher.Config()
+	assert.Equal(t, "quit", (*old)["Default"].Bindings["q"].Command)
+
+	writeConfig(t, path, "[Default.bindings]\nq = \"exit\"\n", 1)
+	select {
+	case config := <-reloaded:
+		assert.Equal(t, "exit", (*config)["Default"].Bindings["q"].Command)
+		assert.Same(t, config, watcher.Config(), "The new config should be swapped in")
+		assert.Equal(t, "quit", (*old)["Default"].Bindings["q"].Command, "The old config should stay unchanged")
+	case err := <-failed:
+		t.Fatalf("Reload failed: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Change was not noticed")
+	}
+
+	current := watcher.Config()
+	writeConfig(t, path, "[Default.bindings]\n\"CTRL-9\" = \"quit\"\n", 2)
+	select {
+	case err := <-failed:
+		var errs keybinding.ConfigErrors
+		assert.ErrorAs(t, err, &errs, "The error handler should get the errors of LoadLayers")
+		assert.Same(t, current, watcher.Config(), "A config with errors should not be swapped in")
+	case <-reloaded:
+		t.Fatal("Invalid config was swapped in")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Change was not noticed")
+	}
+}
+
+func TestWatcher_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	writeConfig(t, path, "[Default.bindings]\nq = \"quit\"\n", 0)
+
+	watcher, err := keybinding.WatchConfig(path, keybinding.WithPollInterval(time.Hour))
+	require.NoError(t, err)
+	defer watcher.Close()
+
+	writeConfig(t, path, "[Default]\ncontext_add = [\"Missing\"]\n", 1)
+	assert.Error(t, watcher.Reload(), "Reload should return the errors of the new config")
+	assert.Equal(t, "quit", (*watcher.Config())["Default"].Bindings["q"].Command, "The old config should stay")
+
+	writeConfig(t, path, "[Default.bindings]\nq = \"exit\"\n", 2)
+	require.NoError(t, watcher.Reload())
+	assert.Equal(t, "exit", (*watcher.Config())["Default"].Bindings["q"].Command)
+}
+
+func TestWatcher_ReloadFromHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	writeConfig(t, path, "[Default.bindings]\nq = \"quit\"\n", 0)
+
+	var watcher *keybinding.Watcher
+	reloads := 0
+	nested := make(chan error, 1)
+	watcher, err := keybinding.WatchConfig(path,
+		keybinding.WithPollInterval(time.Hour),
+		keybinding.OnReload(func(config *types.Config) {
+			reloads++
+			if reloads == 1 {
+				nested <- watcher.Reload()
+			}
+		}),
+	)
+	require.NoError(t, err)
+	defer watcher.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- watcher.Reload()
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+		require.NoError(t, <-nested, "Reload from the reload handler should work")
+		assert.Equal(t, 2, reloads)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Reload from the reload handler deadlocked")
+	}
+}
+
+func TestWatcher_ConcurrentReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	writeConfig(t, path, "[Default.bindings]\nq = \"quit\"\n", 0)
+
+	var mu sync.Mutex
+	var last *types.Config
+	calls := 0
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	watcher, err := keybinding.WatchConfig(path,
+		keybinding.WithPollInterval(10*time.Millisecond),
+		keybinding.OnReload(func(config *types.Config) {
+			mu.Lock()
+			calls++
+			first := calls == 1
+			mu.Unlock()
+			if first {
+				// the reload of the poll loop is slow to hand over its config
+				close(entered)
+				<-release
+			}
+			mu.Lock()
+			last = config
+			mu.Unlock()
+		}),
+	)
+	require.NoError(t, err)
+
+	writeConfig(t, path, "[Default.bindings]\nq = \"exit\"\n", 1)
+	select {
+	case <-entered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Change was not noticed")
+	}
+
+	// a manual reload swaps in a newer config meanwhile
+	done := make(chan error, 1)
+	go func() {
+		done <- watcher.Reload()
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Reload blocked on the running reload handler")
+	}
+	close(release)
+	watcher.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Same(t, watcher.Config(), last, "The reload handler should get the current config last")
+}
+
+func TestWatchConfig_InitialError(t *testing.T) {
+	watcher, err := keybinding.WatchConfig("../testdata/NotThere.toml")
+	assert.Nil(t, watcher)
+	assert.Error(t, err, "A config that doesn't load at first should be an error")
+}
+
+func TestWatchConfig_Include(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	included := filepath.Join(dir, "presets.toml")
+	writeConfig(t, path, "include = [\"presets.toml\"]\n", 0)
+	writeConfig(t, included, "[Default.bindings]\nq = \"quit\"\n", 0)
+
+	reloaded := make(chan *types.Config, 1)
+	watcher, err := keybinding.WatchConfig(path,
+		keybinding.WithPollInterval(10*time.Millisecond),
+		keybinding.OnReload(func(config *types.Config) { reloaded <- config }),
+	)
+	require.NoError(t, err)
+	defer watcher.Close()
+
+	writeConfig(t, included, "[Default.bindings]\nq = \"exit\"\n", 1)
+	select {
+	case config := <-reloaded:
+		assert.Equal(t, "exit", (*config)["Default"].Bindings["q"].Command, "Included files should be watched")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Change was not noticed")
+	}
+}
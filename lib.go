@@ -1,6 +1,7 @@
 package tviewcommand
 
 import (
+	"github.com/spezifisch/tview-command/command"
 	"github.com/spezifisch/tview-command/keybinding"
 	"github.com/spezifisch/tview-command/log"
 	"github.com/spezifisch/tview-command/types"
@@ -9,19 +10,103 @@ import (
 // Re-export functions, types, and variables from keybinding package
 // so it's easier to use for other packages.
 var (
-	LoadConfig     = keybinding.LoadConfig
+	LoadConfig   = keybinding.LoadConfig
+	LoadLayers   = keybinding.LoadLayers
+	LoadConfigFS = keybinding.LoadConfigFS
+
+	LoadConfigFromBytes  = keybinding.LoadConfigFromBytes
+	LoadConfigFromReader = keybinding.LoadConfigFromReader
+	LoadRawConfig        = keybinding.LoadRawConfig
+	LoadRawLayers        = keybinding.LoadRawLayers
+	SaveConfig           = keybinding.SaveConfig
+	DiffConfig           = keybinding.DiffConfig
+
+	DefaultSources = keybinding.DefaultSources
+	WatchConfig    = keybinding.WatchConfig
+	WatchLayers    = keybinding.WatchLayers
 	ValidateConfig = keybinding.ValidateConfig
+	ValidateKeys   = keybinding.ValidateKeys
+
+	ValidateSettings = keybinding.ValidateSettings
+	ValidateCommands = keybinding.ValidateCommands
+	WithRegistry     = keybinding.WithRegistry
+
+	DetectCycleAndValidate = keybinding.DetectCycleAndValidate
+	FindCycles             = keybinding.FindCycles
+
+	CompileExpressions = keybinding.CompileExpressions
+	CompileConditions  = keybinding.CompileConditions
+	WithExprEnv        = keybinding.WithExprEnv
+	WithState          = keybinding.WithState
+	WithStrict         = keybinding.WithStrict
+	WithFormat         = keybinding.WithFormat
+	FormatOf           = keybinding.FormatOf
+	WithLoadOptions    = keybinding.WithLoadOptions
+	WithPollInterval   = keybinding.WithPollInterval
+	OnReload           = keybinding.OnReload
+	OnError            = keybinding.OnError
+
+	NewRegistry = command.NewRegistry
 
 	SetLogHandler = log.SetLogHandler
 	SetLogPrefix  = log.SetLogPrefix
 
 	NewContextStack = types.NewContextStack
 	FromEventKey    = types.FromEventKey
+	ParseKey        = types.ParseKey
+	KeyFromEvent    = types.KeyFromEvent
+	SuggestKey      = types.SuggestKey
+	SuggestName     = types.SuggestName
+
+	ParseKeySequence   = types.ParseKeySequence
+	CanonicalKeyName   = types.CanonicalKeyName
+	NewSequenceMatcher = types.NewSequenceMatcher
+	ParseCommands      = types.ParseCommands
 )
 
 type (
 	Config       = types.Config
 	Context      = types.Context
+	ContextNames = types.ContextNames
+	Binding      = types.Binding
+	Inheritance  = types.Inheritance
+	Step         = types.Step
 	ContextStack = types.ContextStack
 	Event        = types.Event
+	Key          = types.Key
+
+	SequenceMatcher = types.SequenceMatcher
+	SequenceState   = types.SequenceState
+
+	ParsedCommand      = types.Command
+	CommandArg         = types.Arg
+	CommandSyntaxError = types.CommandSyntaxError
+
+	ConfigSource        = keybinding.Source
+	ConfigFormat        = keybinding.Format
+	ConfigWatcher       = keybinding.Watcher
+	InvalidKeyError     = keybinding.InvalidKeyError
+	DuplicateKeyError   = keybinding.DuplicateKeyError
+	CycleError          = keybinding.CycleError
+	UnknownContextError = keybinding.UnknownContextError
+	UnknownFieldError   = keybinding.UnknownFieldError
+	IncludeError        = keybinding.IncludeError
+	IncludeCycleError   = keybinding.IncludeCycleError
+	ConfigError         = keybinding.ConfigError
+	ConfigErrors        = keybinding.ConfigErrors
+	ConfigErrorKind     = keybinding.ErrorKind
+	ConfigPosition      = keybinding.Position
+
+	Registry            = command.Registry
+	Command             = command.Command
+	CommandCall         = command.Call
+	ArgSpec             = command.ArgSpec
+	UnknownCommandError = command.UnknownCommandError
+	ExprEnvFunc         = command.EnvFunc
+)
+
+const (
+	FormatTOML = keybinding.FormatTOML
+	FormatYAML = keybinding.FormatYAML
+	FormatJSON = keybinding.FormatJSON
 )
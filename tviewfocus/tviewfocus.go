@@ -0,0 +1,302 @@
+// Package tviewfocus drives a tview-command ContextStack from the focus of
+// tview primitives, and handles the keys of a tview.Application with the
+// bindings of the focused contexts.
+//
+// It is a module of its own so that the keybinding library doesn't depend on
+// tview.
+package tviewfocus
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/spezifisch/tview-command/command"
+	"github.com/spezifisch/tview-command/log"
+	"github.com/spezifisch/tview-command/types"
+)
+
+// Adapter keeps a ContextStack in sync with the focus of an application.
+//
+// Primitives are attached with a context name. Whenever focus moves, the
+// contexts of all attached primitives that have focus are pushed on top of
+// the contexts the app pushed itself, outer containers below the primitives
+// inside them. A tview.Flex attached as "Queue" with a tview.List inside
+// attached as "Queue.List" gives the stack Global, Queue, Queue.List while
+// the list is focused. Which primitive is inside which is found by walking
+// the items of tview.Flex, tview.Frame and tview.Pages.
+type Adapter struct {
+	// State returns the app state for the "when" conditions of bindings.
+	State func() map[string]interface{}
+	// OnError is called when the lookup or the dispatch of a key fails. The
+	// default logs the error.
+	OnError func(e *types.Event, err error)
+	// OnChange is called with the contexts of the stack after focus moved,
+	// e.g. to show them in a status bar.
+	OnChange func(contexts []string)
+
+	mu       sync.Mutex
+	app      *tview.Application
+	config   *types.Config
+	registry *command.Registry
+	stack    *types.ContextStack
+	matcher  *types.SequenceMatcher
+	attached []attachment
+	base     int // depth of the stack below the adapter's contexts, -1 before the first Sync
+}
+
+type attachment struct {
+	primitive tview.Primitive
+	context   string
+}
+
+// New creates an adapter that looks up keys in config and runs them with
+// registry. Without a registry the adapter only keeps the stack in sync and
+// leaves all keys to the app. Contexts that don't depend on focus, like
+// Global, can be pushed on stack by the app before the first Sync. The
+// adapter's contexts go on top of them, everything above is the adapter's.
+func New(app *tview.Application, config *types.Config, registry *command.Registry, stack *types.ContextStack) *Adapter {
+	a := &Adapter{
+		app:      app,
+		config:   config,
+		registry: registry,
+		stack:    stack,
+		matcher:  types.NewSequenceMatcher(config),
+		base:     -1,
+	}
+	a.matcher.OnTimeout = func(e *types.Event) {
+		a.app.QueueUpdate(func() {
+			a.dispatch(e)
+		})
+	}
+	return a
+}
+
+// Attach assigns a context to a primitive. Attaching the same primitive
+// again replaces its context.
+func (a *Adapter) Attach(primitive tview.Primitive, context string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for i := range a.attached {
+		if a.attached[i].primitive == primitive {
+			a.attached[i].context = context
+			return
+		}
+	}
+	a.attached = append(a.attached, attachment{primitive: primitive, context: context})
+}
+
+// Detach removes the context of a primitive.
+func (a *Adapter) Detach(primitive tview.Primitive) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for i := range a.attached {
+		if a.attached[i].primitive == primitive {
+			a.attached = append(a.attached[:i], a.attached[i+1:]...)
+			return
+		}
+	}
+}
+
+// Install sets the input capture of the application to HandleKey and syncs
+// the stack after every draw, since moving the focus always redraws. Input
+// capture and after draw functions the app set before are kept and run
+// after the adapter's, the input capture only for keys that aren't bound.
+func (a *Adapter) Install() {
+	previousCapture := a.app.GetInputCapture()
+	a.app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		event = a.HandleKey(event)
+		if event != nil && previousCapture != nil {
+			return previousCapture(event)
+		}
+		return event
+	})
+
+	previousAfterDraw := a.app.GetAfterDrawFunc()
+	a.app.SetAfterDrawFunc(func(screen tcell.Screen) {
+		a.Sync()
+		if previousAfterDraw != nil {
+			previousAfterDraw(screen)
+		}
+	})
+}
+
+// HandleKey looks up a key on the stack and dispatches its binding. It
+// returns nil for keys that were handled and the event itself otherwise,
+// so that tview passes it on to the focused primitive.
+//
+// Keys of multi-key bindings like "g g" are consumed while the sequence is
+// pending. If a bound prefix like "g" times out, its binding is dispatched on
+// the event loop of the app. Moving the focus discards a pending sequence.
+func (a *Adapter) HandleKey(event *tcell.EventKey) *tcell.EventKey {
+	a.Sync()
+	if a.registry == nil {
+		return event
+	}
+
+	e := types.FromEventKey(event, a.config)
+	if a.State != nil {
+		e.State = a.State()
+	}
+
+	a.mu.Lock()
+	state, err := a.matcher.FeedStack(e, a.stack)
+	if err == nil && state == types.SequenceAborted && len(e.Sequence) > 1 {
+		// the key doesn't go on with the keys before it, it may start a
+		// sequence of its own
+		state, err = a.matcher.FeedStack(e, a.stack)
+	}
+	if err == nil && state == types.SequenceAborted {
+		_, err = e.LookupStack(a.stack)
+	}
+	a.mu.Unlock()
+	if err != nil {
+		a.fail(e, err)
+		return event
+	}
+	if state == types.SequencePending {
+		return nil
+	}
+	if !e.IsBound {
+		return event
+	}
+
+	a.dispatch(e)
+	return nil
+}
+
+// Sync updates the stack to the focus of the attached primitives. It is
+// called by the hooks of Install, apps only need it to see focus changes
+// right away. It returns the contexts of the stack.
+func (a *Adapter) Sync() []string {
+	a.mu.Lock()
+
+	// HasFocus of containers is true if one of their items has focus
+	var focused []attachment
+	for _, attached := range a.attached {
+		if attached.primitive.HasFocus() {
+			focused = append(focused, attached)
+		}
+	}
+	// focus is on a path from the root to one primitive, so of two focused
+	// primitives one is always inside the other
+	depths := make(map[tview.Primitive]int, len(focused))
+	for _, outer := range focused {
+		for _, inner := range focused {
+			if inner.primitive != outer.primitive && inside(inner.primitive, outer.primitive) {
+				depths[inner.primitive]++
+			}
+		}
+	}
+	sort.SliceStable(focused, func(i, j int) bool {
+		return depths[focused[i].primitive] < depths[focused[j].primitive]
+	})
+
+	contexts := make([]string, len(focused))
+	for i, attached := range focused {
+		contexts[i] = attached.context
+	}
+
+	// the app may have popped contexts below the adapter's in the meantime
+	current := a.stack.Contexts()
+	if a.base < 0 || a.base > len(current) {
+		a.base = len(current)
+	}
+	changed := !equal(contexts, current[a.base:])
+	if changed {
+		for depth := len(current); depth > a.base; depth-- {
+			a.stack.Pop()
+		}
+		for _, context := range contexts {
+			a.stack.Push(context)
+		}
+		a.matcher.Reset()
+	}
+	stack := a.stack.Contexts()
+	onChange := a.OnChange
+	a.mu.Unlock()
+
+	if changed && onChange != nil {
+		onChange(stack)
+	}
+	return stack
+}
+
+func (a *Adapter) dispatch(e *types.Event) {
+	if err := a.registry.Dispatch(e); err != nil {
+		a.fail(e, err)
+	}
+}
+
+func (a *Adapter) fail(e *types.Event, err error) {
+	if a.OnError != nil {
+		a.OnError(e, err)
+		return
+	}
+	log.LogMessage(fmt.Sprintf("Error: key '%s': %v", e.KeyName, err))
+}
+
+// inside reports whether inner is one of the items of outer, or inside one
+// of them. Both must have focus. Containers that don't tell their items, like
+// tview.Grid, contain what has focus and isn't around them, if that can't be
+// told either, what is smaller.
+func inside(inner, outer tview.Primitive) bool {
+	outerItems, known := items(outer)
+	if !known {
+		if _, innerKnown := items(inner); innerKnown {
+			return !inside(outer, inner)
+		}
+		return area(inner) < area(outer)
+	}
+	for _, item := range outerItems {
+		if item == inner || (item != nil && item.HasFocus() && inside(inner, item)) {
+			return true
+		}
+	}
+	return false
+}
+
+// items returns the items of a container, known is false for containers
+// whose items can't be listed.
+func items(primitive tview.Primitive) (items []tview.Primitive, known bool) {
+	switch container := primitive.(type) {
+	case *tview.Flex:
+		for i := 0; i < container.GetItemCount(); i++ {
+			items = append(items, container.GetItem(i))
+		}
+		return items, true
+	case *tview.Frame:
+		return []tview.Primitive{container.GetPrimitive()}, true
+	case *tview.Pages:
+		// only the front page gets focus
+		if _, item := container.GetFrontPage(); item != nil {
+			items = append(items, item)
+		}
+		return items, true
+	case *tview.Grid:
+		return nil, false
+	}
+	return nil, true
+}
+
+func area(primitive tview.Primitive) int {
+	_, _, width, height := primitive.GetRect()
+	return width * height
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
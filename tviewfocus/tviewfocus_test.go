@@ -0,0 +1,160 @@
+package tviewfocus_test
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spezifisch/tview-command/command"
+	"github.com/spezifisch/tview-command/tviewfocus"
+	"github.com/spezifisch/tview-command/types"
+)
+
+func testConfig() *types.Config {
+	return &types.Config{
+		"Global": {Bindings: map[string]types.Binding{"Ctrl+C": {Command: "copy"}}},
+		"Queue":  {Bindings: map[string]types.Binding{"s": {Command: "queue.shuffle"}}},
+		"Queue.List": {Bindings: map[string]types.Binding{
+			"d": {Command: "queue.deleteTrack"},
+			"s": {Command: "queue.sortTracks"},
+		}},
+		"Search": {Bindings: map[string]types.Binding{"ESC": {Command: "search.cancel"}}},
+	}
+}
+
+func TestAdapter(t *testing.T) {
+	var ran []string
+	registry := command.NewRegistry()
+	for _, name := range []string{"copy", "queue.shuffle", "queue.deleteTrack", "queue.sortTracks", "search.cancel"} {
+		registry.MustRegister(command.Command{Name: name, Handler: func(call *command.Call) error {
+			ran = append(ran, call.Name)
+			return nil
+		}})
+	}
+
+	list := tview.NewBox()
+	search := tview.NewBox()
+	queue := tview.NewFlex().
+		AddItem(list, 0, 1, true).
+		AddItem(search, 1, 0, false)
+	queue.SetRect(0, 0, 80, 24)
+	list.SetRect(0, 0, 80, 23)
+	search.SetRect(0, 23, 80, 1)
+
+	stack := types.NewContextStack()
+	var changes [][]string
+	adapter := tviewfocus.New(tview.NewApplication(), testConfig(), registry, stack)
+	adapter.OnChange = func(contexts []string) {
+		changes = append(changes, contexts)
+	}
+	// inner primitives first, the order on the stack comes from the layout
+	adapter.Attach(list, "Queue.List")
+	adapter.Attach(search, "Search")
+	adapter.Attach(queue, "Queue")
+
+	assert.Equal(t, []string{"Global"}, adapter.Sync(), "Nothing has focus yet")
+
+	list.Focus(nil)
+	assert.Equal(t, []string{"Global", "Queue", "Queue.List"}, adapter.Sync())
+	assert.Nil(t, adapter.HandleKey(tcell.NewEventKey(tcell.KeyRune, 's', tcell.ModNone)), "Bound keys should be consumed")
+	assert.Nil(t, adapter.HandleKey(tcell.NewEventKey(tcell.KeyCtrlC, 0, tcell.ModCtrl)), "Keys should fall through to Global")
+
+	list.Blur()
+	search.Focus(nil)
+	assert.Equal(t, []string{"Global", "Queue", "Search"}, adapter.Sync())
+	assert.Nil(t, adapter.HandleKey(tcell.NewEventKey(tcell.KeyRune, 's', tcell.ModNone)))
+
+	unbound := tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModNone)
+	assert.Equal(t, unbound, adapter.HandleKey(unbound), "Unbound keys should be passed on")
+
+	assert.Equal(t, []string{"queue.sortTracks", "copy", "queue.shuffle"}, ran)
+	assert.Equal(t, [][]string{
+		{"Global", "Queue", "Queue.List"},
+		{"Global", "Queue", "Search"},
+	}, changes)
+
+	adapter.Detach(search)
+	assert.Equal(t, []string{"Global", "Queue"}, adapter.Sync())
+}
+
+func TestAdapter_BaseDepth(t *testing.T) {
+	list := tview.NewBox()
+	stack := types.NewContextStack()
+	stack.Push("Player")
+	adapter := tviewfocus.New(tview.NewApplication(), testConfig(), nil, stack)
+	adapter.Attach(list, "Queue.List")
+
+	list.Focus(nil)
+	assert.Equal(t, []string{"Global", "Player", "Queue.List"}, adapter.Sync())
+
+	// the adapter's contexts are replaced, whatever the app did on top of them
+	stack.Push("Popup")
+	assert.Equal(t, []string{"Global", "Player", "Queue.List"}, adapter.Sync())
+
+	// when the app pops its own contexts, the adapter's move down
+	stack.Pop()
+	stack.Pop()
+	assert.Equal(t, []string{"Global", "Queue.List"}, adapter.Sync())
+
+	key := tcell.NewEventKey(tcell.KeyRune, 'd', tcell.ModNone)
+	assert.Equal(t, key, adapter.HandleKey(key), "Without a registry keys should be passed on")
+}
+
+func TestAdapter_Nesting(t *testing.T) {
+	list := tview.NewBox()
+	queue := tview.NewFlex().AddItem(list, 0, 1, true)
+	grid := tview.NewGrid().AddItem(queue, 0, 0, 1, 1, 0, 0, true)
+	pages := tview.NewPages().AddPage("main", grid, true, true)
+	// the list fills all of its containers, only the tree tells them apart
+	for _, primitive := range []tview.Primitive{list, queue, grid, pages} {
+		primitive.SetRect(0, 0, 80, 24)
+	}
+
+	stack := types.NewContextStack()
+	adapter := tviewfocus.New(tview.NewApplication(), testConfig(), nil, stack)
+	adapter.Attach(list, "Queue.List")
+	adapter.Attach(queue, "Queue")
+	adapter.Attach(pages, "Pages")
+	adapter.Attach(grid, "Grid")
+
+	// grid items only have focus once they were drawn
+	screen := tcell.NewSimulationScreen("")
+	require.NoError(t, screen.Init())
+	screen.SetSize(80, 24)
+	pages.Draw(screen)
+
+	list.Focus(nil)
+	assert.Equal(t, []string{"Global", "Pages", "Grid", "Queue", "Queue.List"}, adapter.Sync())
+}
+
+func TestAdapter_Sequences(t *testing.T) {
+	var ran []string
+	registry := command.NewRegistry()
+	for _, name := range []string{"badges.show", "queue.deleteTrack"} {
+		registry.MustRegister(command.Command{Name: name, Handler: func(call *command.Call) error {
+			ran = append(ran, call.Name)
+			return nil
+		}})
+	}
+	config := testConfig()
+	(*config)["Global"].Bindings["SPC b s"] = types.Binding{Command: "badges.show"}
+
+	list := tview.NewBox()
+	adapter := tviewfocus.New(tview.NewApplication(), config, registry, types.NewContextStack())
+	adapter.Attach(list, "Queue.List")
+	list.Focus(nil)
+
+	// a sequence bound in Global completes while Queue.List is focused
+	for _, r := range " bs" {
+		assert.Nil(t, adapter.HandleKey(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone)))
+	}
+
+	// a key that doesn't go on with the sequence is looked up on its own
+	assert.Nil(t, adapter.HandleKey(tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModNone)))
+	assert.Nil(t, adapter.HandleKey(tcell.NewEventKey(tcell.KeyRune, 'd', tcell.ModNone)))
+
+	assert.Equal(t, []string{"badges.show", "queue.deleteTrack"}, ran)
+}
@@ -0,0 +1,388 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/vm"
+)
+
+// Binding is what a key is bound to, either a command chain (see
+// ParseCommands) or an expr-lang expression.
+//
+// In the config a plain string is a command, a table can hold either:
+//
+//	d = "queue.deleteTrack"
+//	x = { command = "queue.deleteTrack" }
+//	y = { expr = "favoriteTrack(CurrentTrackID)" }
+//
+// An inherited key is unbound with false or "nop". The key then stops
+// matching in the context, also for the contexts below it on the stack:
+//
+//	d = false
+//
+// A table can also describe the binding for help screens, and pass
+// arguments to a single command that would need quoting in a string:
+//
+//	d = { command = "queue.deleteTrack", description = "Remove track", category = "Queue" }
+//	/ = { command = "search", args = ["artist:", 10] }
+//
+// A binding can have a condition that is evaluated against the app's state
+// when the key is looked up. A list of bindings makes the key do different
+// things depending on the state, the first one whose condition holds wins:
+//
+//	SPC = [
+//	    { command = "togglePlay", when = "TrackSelected" },
+//	    "openCommandPalette",
+//	]
+type Binding struct {
+	Command string `toml:"command,omitempty"`
+	Expr    string `toml:"expr,omitempty"`
+	When    string `toml:"when,omitempty"`
+	Unbind  bool   `toml:"-"` // set for false and "nop"
+
+	// Description and Category are for help screens and command palettes.
+	Description string `toml:"description,omitempty"`
+	Category    string `toml:"category,omitempty"`
+	// Args are passed to the command after the arguments in Command, see
+	// Commands. Values are strings, ints, floats or bools.
+	Args []interface{} `toml:"args,omitempty"`
+
+	// Alternatives of a key bound to a list. The binding itself has no
+	// command or expression then.
+	Alternatives []Binding `toml:"-"`
+
+	// Provenance is the way the binding got into the context it was
+	// resolved for, see Explain. Shadowed are the inherited bindings of the
+	// same key that it took precedence over. Both are set by LoadConfig.
+	Provenance []Step    `toml:"-"`
+	Shadowed   []Binding `toml:"-"`
+	// Layer is the name of the config source the binding was loaded from,
+	// see keybinding.LoadLayers.
+	Layer string `toml:"-"`
+
+	program     *vm.Program // compiled Expr, see Compile
+	whenProgram *vm.Program // compiled When, see CompileWhen
+}
+
+// NopCommand is the command that unbinds a key, like false.
+const NopCommand = "nop"
+
+// IsExpr reports whether the binding runs an expression instead of commands.
+func (b Binding) IsExpr() bool {
+	return b.Expr != ""
+}
+
+// Candidates returns the alternatives of a binding to a list, or the binding
+// itself.
+func (b Binding) Candidates() []Binding {
+	if b.Alternatives != nil {
+		return b.Alternatives
+	}
+	return []Binding{b}
+}
+
+// String returns the command, or the expression prefixed with "expr:".
+// Conditions and alternatives are included.
+func (b Binding) String() string {
+	if b.Alternatives != nil {
+		alternatives := make([]string, len(b.Alternatives))
+		for i, alternative := range b.Alternatives {
+			alternatives[i] = alternative.String()
+		}
+		return "[" + strings.Join(alternatives, ", ") + "]"
+	}
+
+	s := b.Command
+	if b.Unbind {
+		s = NopCommand
+	} else if b.IsExpr() {
+		s = "expr: " + b.Expr
+	}
+	if b.When != "" {
+		s += " when " + b.When
+	}
+	return s
+}
+
+// UnmarshalTOML decodes a binding from a string, a table or a list of those.
+func (b *Binding) UnmarshalTOML(data interface{}) error {
+	// an array of tables, like [[Queue.bindings.d]]
+	if tables, ok := data.([]map[string]interface{}); ok {
+		list := make([]interface{}, len(tables))
+		for i, table := range tables {
+			list[i] = table
+		}
+		data = list
+	}
+
+	list, ok := data.([]interface{})
+	if !ok {
+		return b.unmarshalSingle(data)
+	}
+
+	if len(list) == 0 {
+		return fmt.Errorf("binding list must not be empty")
+	}
+	*b = Binding{Alternatives: make([]Binding, len(list))}
+	for i, entry := range list {
+		if err := b.Alternatives[i].unmarshalSingle(entry); err != nil {
+			return fmt.Errorf("binding %d of list: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (b *Binding) unmarshalSingle(data interface{}) error {
+	switch value := data.(type) {
+	case string:
+		*b = Binding{Command: value}
+		if value == NopCommand {
+			*b = Binding{Unbind: true}
+		}
+		return nil
+	case bool:
+		if value {
+			return fmt.Errorf("binding must be a command, a table or false, got true")
+		}
+		*b = Binding{Unbind: true}
+		return nil
+	}
+	table, ok := data.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("binding must be a command, a table or false, got %v", data)
+	}
+
+	*b = Binding{}
+	fields := make([]string, 0, len(table))
+	for field := range table {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		if field == "args" {
+			args, err := unmarshalArgs(table[field])
+			if err != nil {
+				return err
+			}
+			b.Args = args
+			continue
+		}
+
+		text, ok := table[field].(string)
+		if !ok {
+			return fmt.Errorf("binding field '%s' must be a string, got %v", field, table[field])
+		}
+		switch field {
+		case "command":
+			b.Command = text
+		case "expr":
+			b.Expr = text
+		case "when":
+			b.When = text
+		case "description":
+			b.Description = text
+		case "category":
+			b.Category = text
+		default:
+			return fmt.Errorf("unknown binding field '%s'", field)
+		}
+	}
+
+	if (b.Command == "") == (b.Expr == "") {
+		return fmt.Errorf("binding needs either 'command' or 'expr'")
+	}
+	if b.Args != nil && b.IsExpr() {
+		return fmt.Errorf("binding field 'args' only applies to 'command'")
+	}
+	return nil
+}
+
+func unmarshalArgs(data interface{}) ([]interface{}, error) {
+	list, ok := data.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("binding field 'args' must be a list, got %v", data)
+	}
+	args := make([]interface{}, len(list))
+	for i, value := range list {
+		switch v := value.(type) {
+		case string, float64, bool:
+			args[i] = v
+		case int64:
+			args[i] = int(v)
+		default:
+			return nil, fmt.Errorf("binding field 'args': argument %d must be a string, number or bool, got %v", i+1, value)
+		}
+	}
+	return args, nil
+}
+
+// Commands parses the command chain of the binding, see ParseCommands. Args
+// are appended to the arguments of the command, so a chain can't have them.
+func (b Binding) Commands() ([]Command, error) {
+	commands, err := ParseCommands(b.Command)
+	if err != nil || len(b.Args) == 0 {
+		return commands, err
+	}
+	if len(commands) != 1 {
+		return nil, fmt.Errorf("binding field 'args' needs a single command, got %d in '%s'", len(commands), b.Command)
+	}
+
+	command := &commands[0]
+	for _, value := range b.Args {
+		arg := Arg{Value: value, Raw: fmt.Sprint(value), Pos: len(b.Command)}
+		_, arg.Quoted = value.(string)
+		command.Args = append(command.Args, arg)
+	}
+	return commands, nil
+}
+
+// Compile compiles the expression of the binding, e.g. with expr.Env(env)
+// to check it against the app's environment. The program is kept in the
+// binding so that it is compiled only once. Command bindings are left alone.
+// Alternatives are compiled one by one, the first error is returned.
+func (b *Binding) Compile(opts ...expr.Option) error {
+	for i := range b.Alternatives {
+		if err := b.Alternatives[i].Compile(opts...); err != nil {
+			return err
+		}
+	}
+	if !b.IsExpr() {
+		return nil
+	}
+
+	program, err := expr.Compile(b.Expr, opts...)
+	if err != nil {
+		return fmt.Errorf("expr '%s': %v", b.Expr, err)
+	}
+	b.program = program
+	return nil
+}
+
+// CompileWhen compiles the condition of the binding and its alternatives,
+// e.g. with expr.Env(state) to check it against the app's state. Conditions
+// must give a bool.
+func (b *Binding) CompileWhen(opts ...expr.Option) error {
+	for i := range b.Alternatives {
+		if err := b.Alternatives[i].CompileWhen(opts...); err != nil {
+			return err
+		}
+	}
+	if b.When == "" {
+		return nil
+	}
+
+	program, err := expr.Compile(b.When, conditionOptions(opts)...)
+	if err != nil {
+		return fmt.Errorf("when '%s': %v", b.When, err)
+	}
+	b.whenProgram = program
+	return nil
+}
+
+// Holds reports whether the condition of the binding holds for state.
+// Bindings without a condition always hold. State entries that are missing
+// count as false.
+func (b Binding) Holds(state map[string]interface{}) (bool, error) {
+	if b.When == "" {
+		return true, nil
+	}
+	if state == nil {
+		state = map[string]interface{}{}
+	}
+
+	program := b.whenProgram
+	if program == nil {
+		var err error
+		if program, err = expr.Compile(b.When, conditionOptions(nil)...); err != nil {
+			return false, err
+		}
+	}
+	result, err := expr.Run(program, state)
+	if err != nil {
+		return false, err
+	}
+	holds, _ := result.(bool)
+	return holds, nil
+}
+
+// conditionOptions returns the options to compile a "when" condition with.
+func conditionOptions(opts []expr.Option) []expr.Option {
+	return append([]expr.Option{expr.AsBool(), expr.Patch(missingFalse{})}, opts...)
+}
+
+// missingFalse patches conditions so that state entries that are missing
+// count as false: they are false where a bool is needed or compared for
+// equality, and ordering comparisons with them don't hold.
+type missingFalse struct{}
+
+func (missingFalse) Visit(node *ast.Node) {
+	switch n := (*node).(type) {
+	case *ast.UnaryNode:
+		if n.Operator == "!" || n.Operator == "not" {
+			n.Node = orFalse(n.Node)
+		}
+	case *ast.ConditionalNode:
+		n.Cond = orFalse(n.Cond)
+	case *ast.BinaryNode:
+		switch n.Operator {
+		case "&&", "||", "and", "or", "==", "!=":
+			n.Left, n.Right = orFalse(n.Left), orFalse(n.Right)
+		case "<", ">", "<=", ">=":
+			var guarded ast.Node = n
+			for _, operand := range []ast.Node{n.Right, n.Left} {
+				if _, ok := operand.(*ast.IdentifierNode); ok {
+					isSet := &ast.BinaryNode{Operator: "!=", Left: operand, Right: &ast.NilNode{}}
+					guarded = &ast.BinaryNode{Operator: "&&", Left: isSet, Right: guarded}
+				}
+			}
+			if guarded != n {
+				ast.Patch(node, guarded)
+			}
+		}
+	}
+}
+
+// orFalse returns "node ?? false" if node is a state entry, node otherwise.
+func orFalse(node ast.Node) ast.Node {
+	if _, ok := node.(*ast.IdentifierNode); !ok {
+		return node
+	}
+	return &ast.BinaryNode{Operator: "??", Left: node, Right: &ast.BoolNode{Value: false}}
+}
+
+// Select returns the first candidate of the binding whose condition holds
+// for state. The bool is false if there is none.
+func (b Binding) Select(state map[string]interface{}) (Binding, bool, error) {
+	for _, candidate := range b.Candidates() {
+		holds, err := candidate.Holds(state)
+		if err != nil {
+			return Binding{}, false, fmt.Errorf("when '%s': %v", candidate.When, err)
+		}
+		if holds {
+			return candidate, true, nil
+		}
+	}
+	return Binding{}, false, nil
+}
+
+// Run runs the expression of the binding against env and returns its result.
+// It is compiled on the fly if Compile wasn't called.
+func (b Binding) Run(env interface{}) (interface{}, error) {
+	if !b.IsExpr() {
+		return nil, fmt.Errorf("binding '%s' is not an expression", b.Command)
+	}
+
+	program := b.program
+	if program == nil {
+		var err error
+		if program, err = expr.Compile(b.Expr); err != nil {
+			return nil, err
+		}
+	}
+	return expr.Run(program, env)
+}
@@ -0,0 +1,185 @@
+package types
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/expr-lang/expr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinding_UnmarshalTOML(t *testing.T) {
+	var context Context
+	_, err := toml.Decode(`bindings = { d = "queue.deleteTrack", x = { command = "queue.deleteTrack" }, y = { expr = "favoriteTrack(CurrentTrackID)" } }`, &context)
+	require.NoError(t, err)
+
+	assert.Equal(t, Binding{Command: "queue.deleteTrack"}, context.Bindings["d"])
+	assert.Equal(t, Binding{Command: "queue.deleteTrack"}, context.Bindings["x"])
+	assert.True(t, context.Bindings["y"].IsExpr())
+	assert.Equal(t, "expr: favoriteTrack(CurrentTrackID)", context.Bindings["y"].String())
+
+	for _, invalid := range []string{
+		`bindings = { y = 1 }`,
+		`bindings = { y = {} }`,
+		`bindings = { y = { exp = "favoriteTrack()" } }`,
+		`bindings = { y = { expr = 1 } }`,
+		`bindings = { y = { command = "a", expr = "b()" } }`,
+	} {
+		_, err := toml.Decode(invalid, &context)
+		assert.Error(t, err, "%s should not decode", invalid)
+	}
+}
+
+func TestBinding_Run(t *testing.T) {
+	env := map[string]interface{}{
+		"CurrentTrackID": "12345",
+		"favoriteTrack":  func(trackID string) string { return "favorited " + trackID },
+		"fail":           func() error { return errors.New("failed") },
+	}
+
+	binding := Binding{Expr: "favoriteTrack(CurrentTrackID)"}
+	require.NoError(t, binding.Compile(expr.Env(env)))
+	result, err := binding.Run(env)
+	assert.NoError(t, err)
+	assert.Equal(t, "favorited 12345", result)
+
+	assert.Error(t, (&Binding{Expr: "favoriteTrack(1, 2)"}).Compile(expr.Env(env)), "Calls should be checked against the environment")
+	assert.NoError(t, (&Binding{Command: "not an expression("}).Compile(expr.Env(env)), "Command bindings aren't compiled")
+
+	_, err = Binding{Command: "favoriteTrack"}.Run(env)
+	assert.Error(t, err)
+
+	result, err = Binding{Expr: "1 + 2"}.Run(nil)
+	assert.NoError(t, err, "Bindings should compile on the fly")
+	assert.Equal(t, 3, result)
+}
+
+func TestBinding_UnmarshalTOMLList(t *testing.T) {
+	var context Context
+	_, err := toml.Decode(`bindings = { SPC = [{ command = "togglePlay", when = "TrackSelected" }, "openCommandPalette"] }`, &context)
+	require.NoError(t, err)
+
+	binding := context.Bindings["SPC"]
+	require.Len(t, binding.Alternatives, 2)
+	assert.Equal(t, Binding{Command: "togglePlay", When: "TrackSelected"}, binding.Alternatives[0])
+	assert.Equal(t, Binding{Command: "openCommandPalette"}, binding.Alternatives[1])
+	assert.Equal(t, "[togglePlay when TrackSelected, openCommandPalette]", binding.String())
+
+	var tables Context
+	_, err = toml.Decode(`[[bindings.SPC]]
+command = "togglePlay"
+when = "TrackSelected"
+[[bindings.SPC]]
+command = "openCommandPalette"`, &tables)
+	require.NoError(t, err, "Arrays of tables should decode as lists")
+	assert.Equal(t, binding, tables.Bindings["SPC"])
+
+	for _, invalid := range []string{
+		`bindings = { SPC = [] }`,
+		`bindings = { SPC = [["togglePlay"]] }`,
+		`bindings = { SPC = [{ when = "TrackSelected" }] }`,
+	} {
+		_, err := toml.Decode(invalid, &context)
+		assert.Error(t, err, "%s should not decode", invalid)
+	}
+}
+
+func TestBinding_Select(t *testing.T) {
+	binding := Binding{Alternatives: []Binding{
+		{Command: "togglePlay", When: "TrackSelected"},
+		{Command: "openCommandPalette", When: "!Modal"},
+	}}
+	state := map[string]interface{}{"TrackSelected": false, "Modal": false}
+	require.NoError(t, binding.CompileWhen(expr.Env(state)))
+
+	selected, found, err := binding.Select(map[string]interface{}{"TrackSelected": true, "Modal": false})
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "togglePlay", selected.Command)
+
+	_, found, err = binding.Select(map[string]interface{}{"TrackSelected": false, "Modal": true})
+	assert.NoError(t, err)
+	assert.False(t, found, "No alternative should match")
+
+	assert.Error(t, (&Binding{Command: "a", When: "TrackID"}).CompileWhen(expr.Env(map[string]interface{}{"TrackID": ""})), "Conditions must be bool")
+	assert.Error(t, (&Binding{Command: "a", When: "Unknown"}).CompileWhen(expr.Env(state)), "Conditions can only use the state")
+}
+
+func TestBinding_HoldsMissing(t *testing.T) {
+	state := map[string]interface{}{"TrackSelected": true}
+	for when, holds := range map[string]bool{
+		"Playing":                   false,
+		"!Playing":                  true,
+		"TrackSelected && !Playing": true,
+		"Playing || TrackSelected":  true,
+		"Playing ? false : true":    true,
+		"Playing == false":          true,
+		"Count > 3":                 false,
+		"3 >= Count":                false,
+		"!(Count > 3)":              true,
+		"Mode == 'search'":          false,
+		"Mode != 'search'":          true,
+	} {
+		binding := Binding{Command: "a", When: when}
+		result, err := binding.Holds(state)
+		if assert.NoError(t, err, when) {
+			assert.Equal(t, holds, result, when)
+		}
+
+		env := map[string]interface{}{"TrackSelected": false, "Playing": false, "Count": 0, "Mode": ""}
+		require.NoError(t, binding.CompileWhen(expr.Env(env)), when)
+		result, err = binding.Holds(state)
+		if assert.NoError(t, err, when) {
+			assert.Equal(t, holds, result, "%s, compiled", when)
+		}
+	}
+}
+
+func TestBinding_UnmarshalTOMLUnbind(t *testing.T) {
+	var context Context
+	_, err := toml.Decode(`bindings = { d = false, x = "nop", SPC = [{ command = "togglePlay", when = "TrackSelected" }, false] }`, &context)
+	require.NoError(t, err)
+
+	assert.Equal(t, Binding{Unbind: true}, context.Bindings["d"])
+	assert.Equal(t, Binding{Unbind: true}, context.Bindings["x"])
+	assert.True(t, context.Bindings["SPC"].Alternatives[1].Unbind)
+	assert.Equal(t, "nop", context.Bindings["d"].String())
+
+	_, err = toml.Decode(`bindings = { d = true }`, &context)
+	assert.Error(t, err, "Only false unbinds a key")
+}
+
+func TestBinding_UnmarshalTOMLMetadata(t *testing.T) {
+	var context Context
+	_, err := toml.Decode(`[bindings]
+d = { command = "queue.deleteTrack", description = "Remove track", category = "Queue" }
+"/" = { command = "search", args = ["artist: Foo", 10, 0.5, true] }`, &context)
+	require.NoError(t, err)
+
+	assert.Equal(t, Binding{Command: "queue.deleteTrack", Description: "Remove track", Category: "Queue"}, context.Bindings["d"])
+
+	commands, err := context.Bindings["/"].Commands()
+	require.NoError(t, err)
+	require.Len(t, commands, 1)
+	var values []interface{}
+	for _, arg := range commands[0].Args {
+		values = append(values, arg.Value)
+	}
+	assert.Equal(t, []interface{}{"artist: Foo", 10, 0.5, true}, values, "Args should keep their types")
+	assert.Equal(t, `search "artist: Foo" 10 0.5 true`, CommandsString(commands))
+
+	_, err = Binding{Command: "search; cursorDown", Args: []interface{}{"x"}}.Commands()
+	assert.EqualError(t, err, "binding field 'args' needs a single command, got 2 in 'search; cursorDown'")
+
+	for _, invalid := range []string{
+		`bindings = { y = { command = "a", args = "b" } }`,
+		`bindings = { y = { command = "a", args = [[1]] } }`,
+		`bindings = { y = { expr = "a()", args = [1] } }`,
+		`bindings = { y = { command = "a", description = 1 } }`,
+	} {
+		_, err := toml.Decode(invalid, &context)
+		assert.Error(t, err, "%s should not decode", invalid)
+	}
+}
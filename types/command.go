@@ -0,0 +1,287 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Operator joins a command to the next one in a chain.
+type Operator int
+
+const (
+	// OpEnd marks the last command of a chain.
+	OpEnd Operator = iota
+	// OpThen (";") runs the next command no matter how this one went.
+	OpThen
+	// OpAnd ("&&") runs the next command only if this one succeeded.
+	OpAnd
+)
+
+func (op Operator) String() string {
+	switch op {
+	case OpEnd:
+		return ""
+	case OpThen:
+		return ";"
+	case OpAnd:
+		return "&&"
+	}
+	return fmt.Sprintf("Operator(%d)", int(op))
+}
+
+// Arg is an argument of a command. Unquoted arguments that look like a
+// number or a bool get that type, everything else is a string.
+type Arg struct {
+	Value  interface{} // string, int, float64 or bool
+	Raw    string      // text of the argument with quotes and escapes removed
+	Quoted bool
+	Pos    int // byte offset in the binding value
+}
+
+// Command is one command of a parsed binding value like
+// "addArtistToQueue; cursorDown" or "favoriteTrack toggle".
+type Command struct {
+	Name string
+	Args []Arg
+	Pos  int      // byte offset in the binding value
+	Next Operator // how the following command is run
+}
+
+// CommandSyntaxError is returned by ParseCommands for malformed values.
+type CommandSyntaxError struct {
+	Input string
+	Pos   int // byte offset of the problem
+	Msg   string
+}
+
+func (e *CommandSyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at column %d of '%s': %s", e.Pos+1, e.Input, e.Msg)
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenThen
+	tokenAnd
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	quoted bool
+	pos    int
+}
+
+// ParseCommands parses a binding value into the chain of commands it runs.
+//
+// Commands are separated by ";" (run the next one regardless) or "&&" (run
+// the next one only if this one succeeded). Each command is a name followed
+// by whitespace separated arguments. Arguments can be quoted with '...'
+// (taken literally) or "..." (backslash escapes allowed), and a backslash
+// escapes the next character outside of quotes.
+func ParseCommands(s string) ([]Command, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var commands []Command
+	var current *Command
+	for _, tok := range tokens {
+		switch tok.kind {
+		case tokenWord:
+			if current == nil {
+				if tok.quoted {
+					return nil, &CommandSyntaxError{Input: s, Pos: tok.pos, Msg: "command name must not be quoted"}
+				}
+				commands = append(commands, Command{Name: tok.text, Pos: tok.pos})
+				current = &commands[len(commands)-1]
+			} else {
+				current.Args = append(current.Args, newArg(tok))
+			}
+		case tokenThen, tokenAnd:
+			if current == nil {
+				return nil, &CommandSyntaxError{Input: s, Pos: tok.pos, Msg: fmt.Sprintf("missing command before '%s'", tok.text)}
+			}
+			if tok.kind == tokenThen {
+				current.Next = OpThen
+			} else {
+				current.Next = OpAnd
+			}
+			current = nil
+		}
+	}
+
+	if len(commands) == 0 {
+		return nil, &CommandSyntaxError{Input: s, Pos: 0, Msg: "empty command"}
+	}
+	last := &commands[len(commands)-1]
+	if current == nil && last.Next == OpAnd {
+		return nil, &CommandSyntaxError{Input: s, Pos: len(s), Msg: "missing command after '&&'"}
+	}
+	// a trailing ";" is harmless
+	last.Next = OpEnd
+
+	return commands, nil
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	var word strings.Builder
+	inWord, quoted, wordPos := false, false, 0
+
+	endWord := func() {
+		if inWord {
+			tokens = append(tokens, token{kind: tokenWord, text: word.String(), quoted: quoted, pos: wordPos})
+			word.Reset()
+			inWord, quoted = false, false
+		}
+	}
+	startWord := func(pos int) {
+		if !inWord {
+			inWord, wordPos = true, pos
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			endWord()
+		case c == ';':
+			endWord()
+			tokens = append(tokens, token{kind: tokenThen, text: ";", pos: i})
+		case c == '&':
+			endWord()
+			if i+1 >= len(s) || s[i+1] != '&' {
+				return nil, &CommandSyntaxError{Input: s, Pos: i, Msg: "unexpected '&', use '&&' to chain commands"}
+			}
+			tokens = append(tokens, token{kind: tokenAnd, text: "&&", pos: i})
+			i++
+		case c == '\\':
+			startWord(i)
+			if i+1 >= len(s) {
+				return nil, &CommandSyntaxError{Input: s, Pos: i, Msg: "backslash at end of value"}
+			}
+			i++
+			word.WriteByte(s[i])
+		case c == '\'':
+			startWord(i)
+			quoted = true
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, &CommandSyntaxError{Input: s, Pos: i, Msg: "unterminated quote"}
+			}
+			word.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+		case c == '"':
+			startWord(i)
+			quoted = true
+			next, err := readDoubleQuoted(s, i, &word)
+			if err != nil {
+				return nil, err
+			}
+			i = next
+		default:
+			startWord(i)
+			word.WriteByte(c)
+		}
+	}
+	endWord()
+
+	return tokens, nil
+}
+
+// readDoubleQuoted copies the "..." string starting at start to word and
+// returns the position of the closing quote.
+func readDoubleQuoted(s string, start int, word *strings.Builder) (int, error) {
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return i, nil
+		case '\\':
+			if i+1 >= len(s) {
+				break
+			}
+			i++
+			switch s[i] {
+			case 'n':
+				word.WriteByte('\n')
+			case 't':
+				word.WriteByte('\t')
+			default:
+				word.WriteByte(s[i])
+			}
+		default:
+			word.WriteByte(s[i])
+		}
+	}
+	return 0, &CommandSyntaxError{Input: s, Pos: start, Msg: "unterminated quote"}
+}
+
+func newArg(tok token) Arg {
+	arg := Arg{Value: tok.text, Raw: tok.text, Quoted: tok.quoted, Pos: tok.pos}
+	if tok.quoted {
+		return arg
+	}
+
+	if i, err := strconv.Atoi(tok.text); err == nil {
+		arg.Value = i
+	} else if f, err := strconv.ParseFloat(tok.text, 64); err == nil && looksNumeric(tok.text) {
+		arg.Value = f
+	} else if tok.text == "true" || tok.text == "false" {
+		arg.Value = tok.text == "true"
+	}
+	return arg
+}
+
+// looksNumeric rules out words that ParseFloat accepts but that are meant
+// as names, like "inf" or "NaN".
+func looksNumeric(s string) bool {
+	for _, r := range s {
+		if unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// String formats the command so that ParseCommands gives it back.
+func (c Command) String() string {
+	var sb strings.Builder
+	sb.WriteString(c.Name)
+	for _, arg := range c.Args {
+		sb.WriteByte(' ')
+		sb.WriteString(arg.String())
+	}
+	return sb.String()
+}
+
+// String formats the argument so that it parses back to the same value.
+func (a Arg) String() string {
+	if !a.Quoted && a.Raw != "" && !strings.ContainsAny(a.Raw, " \t\n\r;&\\'\"") {
+		return a.Raw
+	}
+	return `"` + quoteReplacer.Replace(a.Raw) + `"`
+}
+
+var quoteReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+
+// CommandsString formats a chain of commands in canonical form.
+func CommandsString(commands []Command) string {
+	var sb strings.Builder
+	for i, c := range commands {
+		sb.WriteString(c.String())
+		if i < len(commands)-1 {
+			if c.Next == OpAnd {
+				sb.WriteString(" && ")
+			} else {
+				sb.WriteString("; ")
+			}
+		}
+	}
+	return sb.String()
+}
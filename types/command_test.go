@@ -0,0 +1,130 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCommands_Single(t *testing.T) {
+	commands, err := ParseCommands("favoriteTrack toggle")
+	require.NoError(t, err)
+	require.Len(t, commands, 1)
+
+	assert.Equal(t, "favoriteTrack", commands[0].Name)
+	assert.Equal(t, OpEnd, commands[0].Next)
+	require.Len(t, commands[0].Args, 1)
+	assert.Equal(t, "toggle", commands[0].Args[0].Value)
+	assert.Equal(t, 14, commands[0].Args[0].Pos)
+}
+
+func TestParseCommands_Chain(t *testing.T) {
+	commands, err := ParseCommands("addArtistToQueue; cursorDown && play;")
+	require.NoError(t, err)
+	require.Len(t, commands, 3)
+
+	assert.Equal(t, "addArtistToQueue", commands[0].Name)
+	assert.Equal(t, OpThen, commands[0].Next)
+	assert.Equal(t, "cursorDown", commands[1].Name)
+	assert.Equal(t, 18, commands[1].Pos)
+	assert.Equal(t, OpAnd, commands[1].Next)
+	assert.Equal(t, "play", commands[2].Name)
+	assert.Equal(t, OpEnd, commands[2].Next, "A trailing ';' should be ignored")
+}
+
+func TestParseCommands_TypedArgs(t *testing.T) {
+	commands, err := ParseCommands(`seek 10 -2 0.5 true "42" 'a b' "say \"hi\"\n" x\;y inf`)
+	require.NoError(t, err)
+	require.Len(t, commands, 1)
+
+	var values []interface{}
+	for _, arg := range commands[0].Args {
+		values = append(values, arg.Value)
+	}
+	assert.Equal(t, []interface{}{10, -2, 0.5, true, "42", "a b", "say \"hi\"\n", "x;y", "inf"}, values)
+	assert.True(t, commands[0].Args[4].Quoted)
+	assert.False(t, commands[0].Args[0].Quoted)
+}
+
+func TestParseCommands_Errors(t *testing.T) {
+	tests := []struct {
+		value string
+		pos   int
+		msg   string
+	}{
+		{"", 0, "empty command"},
+		{"   ", 0, "empty command"},
+		{"; play", 0, "missing command before ';'"},
+		{"play &&", 7, "missing command after '&&'"},
+		{"play && && stop", 8, "missing command before '&&'"},
+		{"play & stop", 5, "unexpected '&', use '&&' to chain commands"},
+		{"say 'hello", 4, "unterminated quote"},
+		{`say "hello`, 4, "unterminated quote"},
+		{`say hello\`, 9, "backslash at end of value"},
+		{`"say" hello`, 0, "command name must not be quoted"},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseCommands(tt.value)
+		var syntaxErr *CommandSyntaxError
+		if assert.ErrorAs(t, err, &syntaxErr, "%q should not parse", tt.value) {
+			assert.Equal(t, tt.pos, syntaxErr.Pos, "position of error in %q", tt.value)
+			assert.Equal(t, tt.msg, syntaxErr.Msg, "message of error in %q", tt.value)
+		}
+	}
+
+	_, err := ParseCommands("say 'hello")
+	assert.EqualError(t, err, "syntax error at column 5 of 'say 'hello': unterminated quote")
+}
+
+func TestCommandsString_RoundTrip(t *testing.T) {
+	for _, value := range []string{
+		"favoriteTrack toggle",
+		"addArtistToQueue; cursorDown",
+		"a && b; c 1 2.5 false",
+		`say "two words" "x;y" "quote \" and \\ backslash" "" "42"`,
+	} {
+		commands, err := ParseCommands(value)
+		require.NoError(t, err)
+
+		formatted := CommandsString(commands)
+		again, err := ParseCommands(formatted)
+		require.NoError(t, err, "formatted %q should parse", formatted)
+		assert.Equal(t, CommandsString(commands), CommandsString(again))
+		for i := range commands {
+			assert.Equal(t, len(commands[i].Args), len(again[i].Args))
+			for j := range commands[i].Args {
+				assert.Equal(t, commands[i].Args[j].Value, again[i].Args[j].Value)
+			}
+		}
+	}
+
+	commands, err := ParseCommands("a&&b ;c")
+	require.NoError(t, err)
+	assert.Equal(t, "a && b; c", CommandsString(commands))
+}
+
+func TestLookupCommand_ParsedCommands(t *testing.T) {
+	config := Config{
+		"Browser": Context{
+			Bindings: map[string]Binding{
+				"a": {Command: "addArtistToQueue; cursorDown"},
+				"b": {Command: "broken 'quote"},
+			},
+		},
+	}
+
+	event := FromEventKey(tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone), &config)
+	require.NoError(t, event.LookupCommand("Browser"))
+	require.Len(t, event.Commands, 2)
+	assert.Equal(t, "addArtistToQueue", event.Commands[0].Name)
+	assert.Equal(t, "cursorDown", event.Commands[1].Name)
+
+	event = FromEventKey(tcell.NewEventKey(tcell.KeyRune, 'b', tcell.ModNone), &config)
+	err := event.LookupCommand("Browser")
+	assert.Error(t, err, "Syntax errors should be reported by the lookup")
+	assert.True(t, event.IsBound)
+	assert.Nil(t, event.Commands)
+}
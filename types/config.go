@@ -1,3 +1,15 @@
 package types
 
 type Config map[string]Context
+
+// Parent returns the closest ancestor of a sub-context that is in the
+// config, e.g. "Playlist" for "Playlist.TrackList.Filter" if there is no
+// "Playlist.TrackList", or "" if there is none.
+func (c Config) Parent(contextName string) string {
+	for parent := ParentContext(contextName); parent != ""; parent = ParentContext(parent) {
+		if _, exists := c[parent]; exists {
+			return parent
+		}
+	}
+	return ""
+}
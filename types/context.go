@@ -1,9 +1,137 @@
 package types
 
+import (
+	"fmt"
+	"strings"
+)
+
 // Context represents a specific context or mode in the application.
 type Context struct {
-	Bindings        map[string]string      `toml:"bindings"`
-	ContextAdd      []string               `toml:"context_add,omitempty"`
-	ContextOverride []string               `toml:"context_override,omitempty"`
-	Settings        map[string]interface{} `toml:"settings,omitempty"`
+	Bindings          map[string]Binding     `toml:"bindings"`
+	ContextAdd        ContextNames           `toml:"context_add,omitempty"`
+	ContextOverride   ContextNames           `toml:"context_override,omitempty"`
+	ContextRemoveKeys []string               `toml:"context_remove_keys,omitempty"`
+	Settings          map[string]interface{} `toml:"settings,omitempty"`
+}
+
+// ContextNames is a list of contexts to inherit from. In the config it is
+// an array, a single name or names separated by commas:
+//
+//	context_add = ["ArticlePreset", "ListPreset"]
+//	context_add = "ArticlePreset,ListPreset"
+//	context_override = "Empty"
+type ContextNames []string
+
+// UnmarshalTOML decodes context names from a string or an array of strings.
+// An empty string or array gives an empty list, not nil, so that a config
+// layer can clear the list of the one below it.
+func (n *ContextNames) UnmarshalTOML(data interface{}) error {
+	names := ContextNames{}
+	switch value := data.(type) {
+	case string:
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	case []interface{}:
+		for _, entry := range value {
+			name, ok := entry.(string)
+			if !ok {
+				return fmt.Errorf("context names must be strings, got %v", entry)
+			}
+			names = append(names, strings.TrimSpace(name))
+		}
+	default:
+		return fmt.Errorf("context names must be a string or an array of strings, got %v", data)
+	}
+	*n = names
+	return nil
+}
+
+// Settings of a context that are understood by the library itself.
+const (
+	// SettingOpaque stops a stack lookup at this context, see LookupStack.
+	SettingOpaque = "opaque"
+	// SettingFallthroughKeys lists keys that still fall through an opaque
+	// context, e.g. ["Ctrl+C"].
+	SettingFallthroughKeys = "fallthrough_keys"
+	// SettingSequenceTimeout of the Global context is how long a
+	// SequenceMatcher waits for the next key of a sequence, e.g. "500ms".
+	SettingSequenceTimeout = "sequence_timeout"
+)
+
+// IsOpaque reports whether keys that aren't bound in this context are hidden
+// from the contexts below it on the stack.
+func (c Context) IsOpaque() bool {
+	opaque, _ := c.Settings[SettingOpaque].(bool)
+	return opaque
+}
+
+// FallsThrough reports whether a lookup of keyName continues below this
+// context if the key isn't bound here. That is always the case for contexts
+// that aren't opaque, opaque ones only let the keys of their allow-list pass.
+func (c Context) FallsThrough(keyName string) bool {
+	if !c.IsOpaque() {
+		return true
+	}
+
+	for _, allowed := range c.FallthroughKeys() {
+		if allowed == keyName {
+			return true
+		}
+	}
+	return false
+}
+
+// FallthroughKeys returns the canonical names of the keys listed in the
+// fallthrough_keys setting. Entries that aren't valid keys are skipped.
+func (c Context) FallthroughKeys() []string {
+	var specs []string
+	switch entries := c.Settings[SettingFallthroughKeys].(type) {
+	case []string:
+		specs = entries
+	case []interface{}:
+		for _, entry := range entries {
+			if spec, ok := entry.(string); ok {
+				specs = append(specs, spec)
+			}
+		}
+	}
+
+	var keyNames []string
+	for _, spec := range specs {
+		if keys, err := ParseKeySequence(spec); err == nil {
+			keyNames = append(keyNames, SequenceString(keys))
+		}
+	}
+	return keyNames
+}
+
+// RemovedKeys returns the canonical names of the keys in context_remove_keys.
+// Ranges like "[a-z]" are expanded, see ParseKeyRange. Entries that are
+// neither a key nor a range are returned as they are.
+func (c Context) RemovedKeys() []string {
+	var keyNames []string
+	for _, entry := range c.ContextRemoveKeys {
+		if keys, err := ParseKeyRange(entry); err == nil {
+			for _, key := range keys {
+				keyNames = append(keyNames, key.String())
+			}
+		} else if keys, err := ParseKeySequence(entry); err == nil {
+			keyNames = append(keyNames, SequenceString(keys))
+		} else {
+			keyNames = append(keyNames, entry)
+		}
+	}
+	return keyNames
+}
+
+// ParentContext returns the name of the context a sub-context belongs to,
+// e.g. "Playlist" for "Playlist.TrackList", or "" for top-level contexts.
+func ParentContext(contextName string) string {
+	if i := strings.LastIndex(contextName, "."); i >= 0 {
+		return contextName[:i]
+	}
+	return ""
 }
@@ -0,0 +1,51 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContext_IsOpaque(t *testing.T) {
+	assert.False(t, Context{}.IsOpaque(), "Contexts are transparent by default")
+	assert.False(t, Context{Settings: map[string]interface{}{"opaque": "yes"}}.IsOpaque(), "Only a bool can make a context opaque")
+	assert.True(t, Context{Settings: map[string]interface{}{"opaque": true}}.IsOpaque())
+}
+
+func TestContext_FallsThrough(t *testing.T) {
+	modal := Context{
+		Settings: map[string]interface{}{
+			"opaque":           true,
+			"fallthrough_keys": []interface{}{"CTRL-C", "C-x C-s", "not a key", 42},
+		},
+	}
+
+	assert.Equal(t, []string{"Ctrl+C", "Ctrl+X Ctrl+S"}, modal.FallthroughKeys())
+	assert.True(t, modal.FallsThrough("Ctrl+C"), "Allow-listed keys should fall through")
+	assert.True(t, modal.FallsThrough("Ctrl+X Ctrl+S"))
+	assert.False(t, modal.FallsThrough("q"), "Other keys should be blocked")
+
+	transparent := Context{Settings: map[string]interface{}{"fallthrough_keys": []string{"Ctrl+C"}}}
+	assert.True(t, transparent.FallsThrough("q"), "Transparent contexts let every key through")
+}
+
+func TestContextNames_UnmarshalTOML(t *testing.T) {
+	tests := map[string]struct {
+		data     interface{}
+		expected ContextNames
+	}{
+		"single name":     {"Empty", ContextNames{"Empty"}},
+		"comma-separated": {"ArticlePreset, ListPreset,", ContextNames{"ArticlePreset", "ListPreset"}},
+		"array":           {[]interface{}{"ArticlePreset", "ListPreset"}, ContextNames{"ArticlePreset", "ListPreset"}},
+	}
+	for name, test := range tests {
+		var names ContextNames
+		if assert.NoError(t, names.UnmarshalTOML(test.data), name) {
+			assert.Equal(t, test.expected, names, name)
+		}
+	}
+
+	var names ContextNames
+	assert.EqualError(t, names.UnmarshalTOML(int64(42)), "context names must be a string or an array of strings, got 42")
+	assert.EqualError(t, names.UnmarshalTOML([]interface{}{"Queue", int64(1)}), "context names must be strings, got 1")
+}
@@ -0,0 +1,270 @@
+package types
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Encode writes the config as TOML that LoadConfig reads back to the same
+// config. Contexts are written in sorted order, each with its inheritance
+// fields, its settings and its bindings, with keys by their canonical name
+// and sorted. Sub-contexts are nested tables like [Playlist.TrackList].
+//
+// Encode writes what is in the config. For a config that LoadConfig
+// resolved that includes all inherited bindings, write the unresolved one
+// to keep context_add and context_override, see keybinding.LoadRawConfig.
+func (c Config) Encode(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		if err := c[name].encode(out, name); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+func (c Context) encode(out *bufio.Writer, name string) error {
+	header := encodeKeyPath(strings.Split(name, "."))
+	fmt.Fprintf(out, "[%s]\n", header)
+	if c.ContextAdd != nil {
+		fmt.Fprintf(out, "context_add = %s\n", encodeStrings(c.ContextAdd))
+	}
+	if c.ContextOverride != nil {
+		fmt.Fprintf(out, "context_override = %s\n", encodeStrings(c.ContextOverride))
+	}
+	if c.ContextRemoveKeys != nil {
+		keys := make([]string, len(c.ContextRemoveKeys))
+		for i, key := range c.ContextRemoveKeys {
+			keys[i] = CanonicalKeyName(key)
+		}
+		fmt.Fprintf(out, "context_remove_keys = %s\n", encodeStrings(keys))
+	}
+
+	if len(c.Settings) > 0 {
+		fmt.Fprintf(out, "\n[%s.settings]\n", header)
+		settings := make([]string, 0, len(c.Settings))
+		for setting := range c.Settings {
+			settings = append(settings, setting)
+		}
+		sort.Strings(settings)
+		for _, setting := range settings {
+			value, err := encodeValue(c.Settings[setting])
+			if err != nil {
+				return fmt.Errorf("context '%s': setting '%s': %v", name, setting, err)
+			}
+			fmt.Fprintf(out, "%s = %s\n", encodeKey(setting), value)
+		}
+	}
+
+	if c.Bindings != nil {
+		bindings := make(map[string]Binding, len(c.Bindings))
+		keyNames := make(map[string]string, len(c.Bindings))
+		for keyName, binding := range c.Bindings {
+			canonical := CanonicalKeyName(keyName)
+			if other, exists := keyNames[canonical]; exists {
+				if other > keyName {
+					other, keyName = keyName, other
+				}
+				return fmt.Errorf("context '%s': keys '%s' and '%s' are both '%s'", name, other, keyName, canonical)
+			}
+			keyNames[canonical] = keyName
+			bindings[canonical] = binding
+		}
+		canonicalNames := make([]string, 0, len(bindings))
+		for keyName := range bindings {
+			canonicalNames = append(canonicalNames, keyName)
+		}
+		sort.Strings(canonicalNames)
+
+		fmt.Fprintf(out, "\n[%s.bindings]\n", header)
+		for _, keyName := range canonicalNames {
+			value, err := bindings[keyName].encode()
+			if err != nil {
+				return fmt.Errorf("context '%s': key '%s': %v", name, keyName, err)
+			}
+			fmt.Fprintf(out, "%s = %s\n", encodeKey(keyName), value)
+		}
+	}
+	return nil
+}
+
+// encode returns the binding as a TOML value: false if it unbinds the key,
+// the command if that is all it has, a list for alternatives, or an inline
+// table.
+func (b Binding) encode() (string, error) {
+	if b.Alternatives != nil {
+		alternatives := make([]string, len(b.Alternatives))
+		for i, alternative := range b.Alternatives {
+			value, err := alternative.encode()
+			if err != nil {
+				return "", err
+			}
+			alternatives[i] = value
+		}
+		return "[" + strings.Join(alternatives, ", ") + "]", nil
+	}
+
+	if b.Unbind {
+		return "false", nil
+	}
+	if b.Expr == "" && b.When == "" && b.Description == "" && b.Category == "" && b.Args == nil {
+		return encodeString(b.Command), nil
+	}
+
+	var fields []string
+	if b.Command != "" {
+		fields = append(fields, "command = "+encodeString(b.Command))
+	}
+	if b.Expr != "" {
+		fields = append(fields, "expr = "+encodeString(b.Expr))
+	}
+	if b.Args != nil {
+		args, err := encodeValue(b.Args)
+		if err != nil {
+			return "", fmt.Errorf("args: %v", err)
+		}
+		fields = append(fields, "args = "+args)
+	}
+	if b.When != "" {
+		fields = append(fields, "when = "+encodeString(b.When))
+	}
+	if b.Description != "" {
+		fields = append(fields, "description = "+encodeString(b.Description))
+	}
+	if b.Category != "" {
+		fields = append(fields, "category = "+encodeString(b.Category))
+	}
+	return "{ " + strings.Join(fields, ", ") + " }", nil
+}
+
+// encodeValue returns a setting or argument as a TOML value.
+func encodeValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return encodeString(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return encodeFloat(v), nil
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, entry := range v {
+			encoded, err := encodeValue(entry)
+			if err != nil {
+				return "", err
+			}
+			values[i] = encoded
+		}
+		return "[" + strings.Join(values, ", ") + "]", nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fields := make([]string, len(keys))
+		for i, key := range keys {
+			encoded, err := encodeValue(v[key])
+			if err != nil {
+				return "", err
+			}
+			fields[i] = encodeKey(key) + " = " + encoded
+		}
+		return "{ " + strings.Join(fields, ", ") + " }", nil
+	}
+	return "", fmt.Errorf("cannot encode %T value %v", value, value)
+}
+
+func encodeFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIn") {
+		s += ".0"
+	}
+	return s
+}
+
+func encodeStrings(values []string) string {
+	encoded := make([]string, len(values))
+	for i, value := range values {
+		encoded[i] = encodeString(value)
+	}
+	return "[" + strings.Join(encoded, ", ") + "]"
+}
+
+// encodeKeyPath returns a dotted TOML key, quoting the parts that need it.
+func encodeKeyPath(path []string) string {
+	parts := make([]string, len(path))
+	for i, part := range path {
+		parts[i] = encodeKey(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// encodeKey returns key bare if TOML allows it, quoted otherwise.
+func encodeKey(key string) string {
+	if key == "" {
+		return `""`
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return encodeString(key)
+		}
+	}
+	return key
+}
+
+// encodeString returns s as a TOML basic string.
+func encodeString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f || r == utf8.RuneError {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,66 @@
+package types
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Encode(t *testing.T) {
+	config := Config{
+		"Queue": {
+			ContextAdd:        ContextNames{"ListPreset"},
+			ContextRemoveKeys: []string{"CTRL-x"},
+			Settings:          map[string]interface{}{"wrap": true, "ratio": 1.0, "title": "Queue \"1\""},
+			Bindings: map[string]Binding{
+				"d":      {Command: "queue.deleteTrack"},
+				"ctrl-c": {Unbind: true},
+				"/":      {Command: "search", Args: []interface{}{"artist: Foo", 10}, Description: "Search"},
+				"SPC":    {Alternatives: []Binding{{Command: "togglePlay", When: "TrackSelected"}, {Command: "openCommandPalette"}}},
+				"y":      {Expr: "favoriteTrack(CurrentTrackID)"},
+			},
+		},
+		"Playlist.TrackList": {Bindings: map[string]Binding{"g g": {Command: "goToTop"}}},
+		"Empty":              {},
+		"Search":             {ContextOverride: ContextNames{}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, config.Encode(&buf))
+	assert.Equal(t, `[Empty]
+
+[Playlist.TrackList]
+
+[Playlist.TrackList.bindings]
+"g g" = "goToTop"
+
+[Queue]
+context_add = ["ListPreset"]
+context_remove_keys = ["Ctrl+X"]
+
+[Queue.settings]
+ratio = 1.0
+title = "Queue \"1\""
+wrap = true
+
+[Queue.bindings]
+"/" = { command = "search", args = ["artist: Foo", 10], description = "Search" }
+"Ctrl+C" = false
+SPC = [{ command = "togglePlay", when = "TrackSelected" }, "openCommandPalette"]
+d = "queue.deleteTrack"
+y = { expr = "favoriteTrack(CurrentTrackID)" }
+
+[Search]
+context_override = []
+`, buf.String())
+}
+
+func TestConfig_EncodeErrors(t *testing.T) {
+	duplicate := Config{"Global": {Bindings: map[string]Binding{"Ctrl-C": {Command: "copy"}, "ctrl+c": {Command: "cancel"}}}}
+	assert.EqualError(t, duplicate.Encode(&bytes.Buffer{}), "context 'Global': keys 'Ctrl-C' and 'ctrl+c' are both 'Ctrl+C'")
+
+	unsupported := Config{"Global": {Settings: map[string]interface{}{"handler": func() {}}}}
+	assert.ErrorContains(t, unsupported.Encode(&bytes.Buffer{}), "context 'Global': setting 'handler': cannot encode func()")
+}
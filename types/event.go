@@ -7,36 +7,44 @@ import (
 )
 
 type Event struct {
+	Key           Key
 	KeyName       string
+	Sequence      []Key // keys of a multi-key binding, see SequenceMatcher
 	Command       string
+	Commands      []Command // Command parsed by ParseCommands
+	Binding       Binding   // the whole binding, e.g. for expression bindings
 	IsBound       bool
+	Context       string // context the command was found in
 	OriginalEvent *tcell.EventKey
 	Config        *Config
+	State         map[string]interface{} // app state for the "when" conditions of bindings
 }
 
 // FromEventKey creates a new Event from a tcell.EventKey and sets the config
 func FromEventKey(ev *tcell.EventKey, config *Config) *Event {
-	e := &Event{
+	key := KeyFromEvent(ev)
+	return &Event{
+		Key:           key,
+		KeyName:       key.String(), // canonical name, as used in Config bindings
 		OriginalEvent: ev,
 		Config:        config, // Assign the config
 	}
-
-	if ev.Key() == tcell.KeyRune {
-		e.KeyName = fmt.Sprintf("%c", ev.Rune())
-	} else {
-		e.KeyName = ev.Name()
-	}
-
-	return e
 }
 
 func (e *Event) String() string {
-	if e.IsBound {
+	if e.IsBound && e.Binding.IsExpr() {
+		return fmt.Sprintf("Key: %s, Expr: %s, Context: %s", e.KeyName, e.Binding.Expr, e.Context)
+	} else if e.IsBound && e.Context != "" {
+		return fmt.Sprintf("Key: %s, Command: %s, Context: %s", e.KeyName, e.Command, e.Context)
+	} else if e.IsBound {
 		return fmt.Sprintf("Key: %s, Command: %s", e.KeyName, e.Command)
 	}
 	return fmt.Sprintf("Key: %s (unbound)", e.KeyName)
 }
 
+// LookupCommand looks up the event's key in the given context. If the key is
+// bound to a list of alternatives, the first one whose "when" condition holds
+// for e.State is taken. The event is unbound if none does.
 func (e *Event) LookupCommand(contextKey string) error {
 	if e.Config == nil {
 		return fmt.Errorf("tviewcommand.types.Event.Config is nil")
@@ -45,19 +53,101 @@ func (e *Event) LookupCommand(contextKey string) error {
 	// Fetch the current context based on the contextKey
 	currentContext, ok := (*e.Config)[contextKey]
 	if !ok {
-		e.IsBound = false
-		e.Command = ""
+		e.unbind()
 		return fmt.Errorf("Lookup failed: Context '%s' not found.", contextKey)
 	}
 
 	// Check if the KeyName from the event has a command bound to it in the current context
-	if command, found := currentContext.Bindings[e.KeyName]; found {
-		e.Command = command
-		e.IsBound = true
-	} else {
-		e.Command = ""
-		e.IsBound = false
+	binding, found, err := e.selectBinding(currentContext)
+	if err != nil || !found || binding.Unbind {
+		e.unbind()
+		return err
+	}
+	return e.bind(binding, contextKey)
+}
+
+// LookupStack looks up the event's key in all contexts on the stack, starting
+// with the current (topmost) one and falling through to Global at the bottom.
+// The first context that binds the key, with a "when" condition that holds
+// for e.State, wins. A key that is explicitly unbound in a context, e.g. with
+// "d = false", ends the lookup. Contexts on the stack that have no section in
+// the config are skipped. An opaque context ends the lookup, unless the key
+// is in its fallthrough_keys setting.
+//
+// It returns the stack layer that matched, counted from the bottom like
+// ContextStack.Contexts, or -1 if no context binds the key.
+func (e *Event) LookupStack(stack *ContextStack) (int, error) {
+	e.unbind()
+
+	if e.Config == nil {
+		return -1, fmt.Errorf("tviewcommand.types.Event.Config is nil")
+	}
+
+	contexts := stack.Contexts()
+	for layer := len(contexts) - 1; layer >= 0; layer-- {
+		context, ok := (*e.Config)[contexts[layer]]
+		if !ok {
+			continue
+		}
+
+		binding, found, err := e.selectBinding(context)
+		if err != nil {
+			return -1, err
+		}
+		if found && binding.Unbind {
+			// explicitly unbound, the contexts below don't get the key either
+			break
+		}
+		if found {
+			return layer, e.bind(binding, contexts[layer])
+		}
+
+		if !context.FallsThrough(e.KeyName) {
+			break
+		}
 	}
 
-	return nil
+	return -1, nil
+}
+
+// selectBinding returns the binding of the event's key in context whose
+// "when" condition holds for e.State, see Binding.Select.
+func (e *Event) selectBinding(context Context) (Binding, bool, error) {
+	binding, found := context.Bindings[e.KeyName]
+	if !found {
+		return Binding{}, false, nil
+	}
+
+	selected, found, err := binding.Select(e.State)
+	if err != nil {
+		return Binding{}, false, fmt.Errorf("key '%s': %v", e.KeyName, err)
+	}
+	return selected, found, nil
+}
+
+// bind sets the binding found for the event. The returned error is a
+// CommandSyntaxError if the command doesn't parse, or the args of the binding
+// don't fit it, the event is bound anyway.
+// Expression bindings have no Command.
+func (e *Event) bind(binding Binding, contextKey string) error {
+	e.Command = binding.Command
+	e.Binding = binding
+	e.IsBound = true
+	e.Context = contextKey
+
+	if binding.IsExpr() {
+		e.Commands = nil
+		return nil
+	}
+	commands, err := binding.Commands()
+	e.Commands = commands
+	return err
+}
+
+func (e *Event) unbind() {
+	e.Command = ""
+	e.Commands = nil
+	e.Binding = Binding{}
+	e.IsBound = false
+	e.Context = ""
 }
@@ -23,6 +23,14 @@ func TestFromEventKey_SpecialKey(t *testing.T) {
 	assert.Equal(t, ev, event.OriginalEvent)
 }
 
+func TestFromEventKey_CtrlKey(t *testing.T) {
+	ev := tcell.NewEventKey(tcell.KeyCtrlC, 0, tcell.ModCtrl)
+	event := FromEventKey(ev, nil)
+
+	assert.Equal(t, "Ctrl+C", event.KeyName)
+	assert.Equal(t, Key{Mod: tcell.ModCtrl, Key: tcell.KeyCtrlC}, event.Key)
+}
+
 func TestString_BoundCommand(t *testing.T) {
 	event := &Event{
 		KeyName: "Enter",
@@ -47,8 +55,8 @@ func TestString_UnboundCommand(t *testing.T) {
 func TestLookupCommand_Bound(t *testing.T) {
 	config := Config{
 		"Main": Context{
-			Bindings: map[string]string{
-				"Enter": "Submit",
+			Bindings: map[string]Binding{
+				"Enter": {Command: "Submit"},
 			},
 		},
 	}
@@ -64,7 +72,7 @@ func TestLookupCommand_Bound(t *testing.T) {
 func TestLookupCommand_Unbound(t *testing.T) {
 	config := Config{
 		"Main": Context{
-			Bindings: map[string]string{},
+			Bindings: map[string]Binding{},
 		},
 	}
 
@@ -79,8 +87,8 @@ func TestLookupCommand_Unbound(t *testing.T) {
 func TestLookupCommand_ContextKeyWithRunePattern(t *testing.T) {
 	config := Config{
 		"a": Context{
-			Bindings: map[string]string{
-				"a": "ActionA",
+			Bindings: map[string]Binding{
+				"a": {Command: "ActionA"},
 			},
 		},
 	}
@@ -96,8 +104,8 @@ func TestLookupCommand_ContextKeyWithRunePattern(t *testing.T) {
 func TestLookupCommand_ContextKeyNotFound(t *testing.T) {
 	config := Config{
 		"Main": Context{
-			Bindings: map[string]string{
-				"Enter": "Submit",
+			Bindings: map[string]Binding{
+				"Enter": {Command: "Submit"},
 			},
 		},
 	}
@@ -111,3 +119,177 @@ func TestLookupCommand_ContextKeyNotFound(t *testing.T) {
 	assert.False(t, event.IsBound)
 	assert.Equal(t, "", event.Command)
 }
+
+func stackConfig() Config {
+	return Config{
+		"Global": Context{
+			Bindings: map[string]Binding{
+				"Ctrl+C": {Command: "copy"},
+				"ESC":    {Command: "closeModal"},
+			},
+		},
+		"QueuePage": Context{
+			Bindings: map[string]Binding{
+				"ESC": {Command: "queue.back"},
+				"d":   {Command: "queue.deleteTrack"},
+			},
+		},
+		"QueueList": Context{
+			Bindings: map[string]Binding{
+				"d": {Command: "queue.list.deleteTrack"},
+			},
+		},
+	}
+}
+
+func TestLookupStack_TopContextWins(t *testing.T) {
+	config := stackConfig()
+	stack := NewContextStack()
+	stack.Push("QueuePage")
+	stack.Push("QueueList")
+
+	event := FromEventKey(tcell.NewEventKey(tcell.KeyRune, 'd', tcell.ModNone), &config)
+	layer, err := event.LookupStack(stack)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, layer)
+	assert.True(t, event.IsBound)
+	assert.Equal(t, "queue.list.deleteTrack", event.Command)
+	assert.Equal(t, "QueueList", event.Context)
+}
+
+func TestLookupStack_FallsThroughToGlobal(t *testing.T) {
+	config := stackConfig()
+	stack := NewContextStack()
+	stack.Push("QueuePage")
+	stack.Push("QueueList")
+
+	event := FromEventKey(tcell.NewEventKey(tcell.KeyEsc, 0, tcell.ModNone), &config)
+	layer, err := event.LookupStack(stack)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, layer, "ESC should be found in QueuePage before Global")
+	assert.Equal(t, "queue.back", event.Command)
+
+	event = FromEventKey(tcell.NewEventKey(tcell.KeyCtrlC, 0, tcell.ModCtrl), &config)
+	layer, err = event.LookupStack(stack)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, layer)
+	assert.Equal(t, "copy", event.Command)
+	assert.Equal(t, "Global", event.Context)
+	assert.Equal(t, "Key: Ctrl+C, Command: copy, Context: Global", event.String())
+}
+
+func TestLookupStack_UnboundAndMissingContexts(t *testing.T) {
+	config := stackConfig()
+	stack := NewContextStack()
+	stack.Push("NotInConfig")
+
+	event := FromEventKey(tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModNone), &config)
+	layer, err := event.LookupStack(stack)
+
+	assert.NoError(t, err, "Contexts without config section should be skipped")
+	assert.Equal(t, -1, layer)
+	assert.False(t, event.IsBound)
+	assert.Equal(t, "", event.Context)
+}
+
+func TestLookupStack_NilConfig(t *testing.T) {
+	event := FromEventKey(tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModNone), nil)
+	layer, err := event.LookupStack(NewContextStack())
+
+	assert.Error(t, err)
+	assert.Equal(t, -1, layer)
+}
+
+func TestLookupStack_OpaqueContext(t *testing.T) {
+	config := stackConfig()
+	config["Modal"] = Context{
+		Bindings: map[string]Binding{
+			"ESC":   {Command: "closeModal"},
+			"Enter": {Command: "confirmAction"},
+		},
+		Settings: map[string]interface{}{
+			"opaque":           true,
+			"fallthrough_keys": []interface{}{"Ctrl-C"},
+		},
+	}
+	stack := NewContextStack()
+	stack.Push("QueuePage")
+	stack.Push("Modal")
+
+	event := FromEventKey(tcell.NewEventKey(tcell.KeyRune, 'd', tcell.ModNone), &config)
+	layer, err := event.LookupStack(stack)
+	assert.NoError(t, err)
+	assert.Equal(t, -1, layer, "Modal should hide QueuePage's 'd'")
+	assert.False(t, event.IsBound)
+
+	event = FromEventKey(tcell.NewEventKey(tcell.KeyCtrlC, 0, tcell.ModCtrl), &config)
+	layer, err = event.LookupStack(stack)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, layer, "Ctrl+C is allowed to fall through the Modal")
+	assert.Equal(t, "copy", event.Command)
+
+	event = FromEventKey(tcell.NewEventKey(tcell.KeyEsc, 0, tcell.ModNone), &config)
+	layer, err = event.LookupStack(stack)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, layer, "Opaque contexts still handle their own keys")
+	assert.Equal(t, "closeModal", event.Command)
+}
+
+func TestLookupCommand_When(t *testing.T) {
+	config := Config{
+		"Default": Context{
+			Bindings: map[string]Binding{
+				"SPC": {Alternatives: []Binding{
+					{Command: "togglePlay", When: "TrackSelected"},
+					{Command: "openCommandPalette"},
+				}},
+				"d": {Command: "deleteTrack", When: "TrackSelected && !Playing"},
+			},
+		},
+	}
+
+	tests := []struct {
+		key      rune
+		state    map[string]interface{}
+		expected string
+	}{
+		{' ', map[string]interface{}{"TrackSelected": true}, "togglePlay"},
+		{' ', map[string]interface{}{"TrackSelected": false}, "openCommandPalette"},
+		{' ', nil, "openCommandPalette"},
+		{'d', map[string]interface{}{"TrackSelected": true, "Playing": false}, "deleteTrack"},
+		{'d', map[string]interface{}{"TrackSelected": true, "Playing": true}, ""},
+	}
+
+	for _, tt := range tests {
+		event := FromEventKey(tcell.NewEventKey(tcell.KeyRune, tt.key, tcell.ModNone), &config)
+		event.State = tt.state
+		assert.NoError(t, event.LookupCommand("Default"))
+		assert.Equal(t, tt.expected != "", event.IsBound, "key %q with state %v", tt.key, tt.state)
+		assert.Equal(t, tt.expected, event.Command, "key %q with state %v", tt.key, tt.state)
+	}
+}
+
+func TestLookupStack_WhenFallsThrough(t *testing.T) {
+	config := stackConfig()
+	queueList := config["QueueList"]
+	queueList.Bindings["ESC"] = Binding{Command: "queue.list.clearSelection", When: "Selection > 0"}
+	config["QueueList"] = queueList
+
+	stack := NewContextStack()
+	stack.Push("QueuePage")
+	stack.Push("QueueList")
+
+	event := FromEventKey(tcell.NewEventKey(tcell.KeyEsc, 0, tcell.ModNone), &config)
+	event.State = map[string]interface{}{"Selection": 2}
+	layer, err := event.LookupStack(stack)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, layer)
+	assert.Equal(t, "queue.list.clearSelection", event.Command)
+
+	event.State = map[string]interface{}{"Selection": 0}
+	layer, err = event.LookupStack(stack)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, layer, "A binding whose condition doesn't hold should fall through")
+	assert.Equal(t, "queue.back", event.Command)
+}
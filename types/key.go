@@ -0,0 +1,456 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Key is a single key press: a tcell key code, the rune for printable keys,
+// and the modifiers held down. Keys parsed from the config and keys built
+// from tcell events are normalized the same way, so they compare with ==.
+type Key struct {
+	Mod  tcell.ModMask
+	Key  tcell.Key
+	Rune rune
+}
+
+// keyNames maps lowercase key names (and aliases) accepted in configs to
+// their tcell key codes. It is filled from tcell.KeyNames in init.
+var keyNames = map[string]tcell.Key{
+	"esc":       tcell.KeyEsc,
+	"escape":    tcell.KeyEsc,
+	"ret":       tcell.KeyEnter,
+	"return":    tcell.KeyEnter,
+	"cr":        tcell.KeyEnter,
+	"bs":        tcell.KeyBackspace2,
+	"backspace": tcell.KeyBackspace2,
+	"del":       tcell.KeyDelete,
+	"ins":       tcell.KeyInsert,
+	"pageup":    tcell.KeyPgUp,
+	"pagedown":  tcell.KeyPgDn,
+}
+
+// spaceNames are the accepted spellings of the space bar, which tcell
+// reports as a rune rather than a key code.
+var spaceNames = map[string]bool{
+	"spc":   true,
+	"space": true,
+}
+
+// canonicalNames overrides tcell's name of a key code in Key.String.
+var canonicalNames = map[tcell.Key]string{
+	tcell.KeyEsc:        "ESC",
+	tcell.KeyBackspace2: "Backspace",
+}
+
+func init() {
+	for k, name := range tcell.KeyNames {
+		if strings.HasPrefix(name, "Ctrl-") {
+			// control keys are spelled with a modifier, see ParseKey
+			continue
+		}
+		lower := strings.ToLower(name)
+		if _, exists := keyNames[lower]; !exists {
+			keyNames[lower] = k
+		}
+	}
+}
+
+// ParseKey parses a key specification as written in a config file.
+//
+// Modifiers are separated from the key by "+" or "-" and may be spelled out
+// ("Ctrl", "Alt", "Shift") or abbreviated Emacs-style ("C", "M"/"A", "S").
+// Vim-style angle brackets are stripped, so "CTRL-C", "Ctrl+c", "C-c" and
+// "<C-c>" are all the same key. Named keys ("enter", "ESC", "SPC", "F1", ...)
+// are case-insensitive, single characters are taken literally.
+func ParseKey(s string) (Key, error) {
+	spec := s
+	if trimmed := strings.TrimSpace(s); trimmed != "" {
+		// a lone " " is the space bar
+		spec = trimmed
+	}
+	if len(spec) > 2 && strings.HasPrefix(spec, "<") && strings.HasSuffix(spec, ">") {
+		spec = spec[1 : len(spec)-1]
+	}
+	if spec == "" {
+		return Key{}, fmt.Errorf("empty key specification")
+	}
+
+	var mod tcell.ModMask
+	for {
+		m, rest, ok := cutModifier(spec)
+		if !ok {
+			break
+		}
+		mod |= m
+		spec = rest
+	}
+
+	var k Key
+	if utf8.RuneCountInString(spec) == 1 {
+		r, _ := utf8.DecodeRuneInString(spec)
+		k = Key{Key: tcell.KeyRune, Rune: r}
+	} else if lower := strings.ToLower(spec); spaceNames[lower] {
+		k = Key{Key: tcell.KeyRune, Rune: ' '}
+	} else if code, found := keyNames[lower]; found {
+		k = Key{Key: code}
+	} else {
+		return Key{}, fmt.Errorf("unknown key name '%s' in '%s'", spec, s)
+	}
+	k.Mod = mod
+
+	return k.normalize(), nil
+}
+
+// cutModifier splits a leading modifier like "Ctrl+" or "C-" off spec.
+// It only succeeds if something is left after the separator, so that "-"
+// and "C" on their own are still plain keys.
+func cutModifier(spec string) (tcell.ModMask, string, bool) {
+	i := strings.IndexAny(spec, "+-")
+	if i <= 0 || i == len(spec)-1 {
+		return tcell.ModNone, spec, false
+	}
+
+	var mod tcell.ModMask
+	switch strings.ToLower(spec[:i]) {
+	case "c", "ctrl", "control":
+		mod = tcell.ModCtrl
+	case "m", "a", "alt", "meta":
+		// tcell reports Meta as Alt on all terminals
+		mod = tcell.ModAlt
+	case "s", "shift":
+		mod = tcell.ModShift
+	default:
+		return tcell.ModNone, spec, false
+	}
+	return mod, spec[i+1:], true
+}
+
+// KeyFromEvent converts a tcell key event into a Key.
+func KeyFromEvent(ev *tcell.EventKey) Key {
+	k := Key{
+		Mod: ev.Modifiers(),
+		Key: ev.Key(),
+	}
+	if k.Key == tcell.KeyRune {
+		k.Rune = ev.Rune()
+	}
+	return k.normalize()
+}
+
+// normalize brings equivalent spellings of the same key press into one form:
+// control characters are key codes with ModCtrl set, shifted letters are
+// upper case runes, and both Backspace codes are KeyBackspace2.
+func (k Key) normalize() Key {
+	if k.Key == tcell.KeyRune {
+		switch {
+		case k.Mod&tcell.ModCtrl != 0 && k.Rune < unicode.MaxASCII && unicode.IsLetter(k.Rune):
+			k.Key = tcell.KeyCtrlA + tcell.Key(unicode.ToLower(k.Rune)-'a')
+		case k.Mod&tcell.ModCtrl != 0 && k.Rune == ' ':
+			k.Key = tcell.KeyCtrlSpace
+		case k.Mod&tcell.ModShift != 0 && unicode.IsLetter(k.Rune):
+			k.Rune = unicode.ToUpper(k.Rune)
+			k.Mod &^= tcell.ModShift
+		}
+		if k.Key == tcell.KeyRune && k.Mod&tcell.ModCtrl != 0 {
+			switch k.Rune {
+			case '@':
+				k.Key = tcell.KeyCtrlSpace
+			case '[', '\\', ']', '^', '_':
+				k.Key = tcell.KeyEsc + tcell.Key(k.Rune-'[')
+			}
+		}
+	}
+
+	if k.Key == tcell.KeyTab && k.Mod&tcell.ModShift != 0 {
+		k.Key = tcell.KeyBacktab
+		k.Mod &^= tcell.ModShift
+	}
+	if k.Key == tcell.KeyBackspace && k.Mod&tcell.ModCtrl == 0 {
+		k.Key = tcell.KeyBackspace2
+	}
+	if isControlCode(k.Key) && !isTypeableControl(k.Key) {
+		k.Mod |= tcell.ModCtrl
+	}
+	if k.Key != tcell.KeyRune {
+		k.Rune = 0
+	}
+	// tcell never reports Meta, see cutModifier
+	if k.Mod&tcell.ModMeta != 0 {
+		k.Mod = k.Mod&^tcell.ModMeta | tcell.ModAlt
+	}
+	return k
+}
+
+// isControlCode reports whether code is an ASCII control character.
+func isControlCode(code tcell.Key) bool {
+	return code >= tcell.KeyCtrlSpace && code <= tcell.KeyCtrlUnderscore
+}
+
+// isTypeableControl reports whether code is a control character that has a
+// key of its own, so it arrives without ModCtrl unless Ctrl was held.
+func isTypeableControl(code tcell.Key) bool {
+	switch code {
+	case tcell.KeyBackspace, tcell.KeyTab, tcell.KeyEnter, tcell.KeyEsc:
+		return true
+	}
+	return false
+}
+
+// controlName returns the character that is pressed together with Ctrl to
+// produce the control code, e.g. "C" for KeyCtrlC.
+func controlName(code tcell.Key) string {
+	switch {
+	case code == tcell.KeyCtrlSpace:
+		return "Space"
+	case code >= tcell.KeyCtrlA && code <= tcell.KeyCtrlZ:
+		return string(rune('A' + code - tcell.KeyCtrlA))
+	default:
+		return string(rune('[' + code - tcell.KeyEsc))
+	}
+}
+
+// String returns the canonical name of the key, e.g. "Ctrl+C", "ESC",
+// "SPC", "Enter", "Ctrl+Enter", "Alt+x" or "G". ParseKey accepts all
+// canonical names.
+func (k Key) String() string {
+	var sb strings.Builder
+	mod := k.Mod
+	ctrlCode := isControlCode(k.Key) && mod&tcell.ModCtrl != 0
+	if ctrlCode {
+		mod &^= tcell.ModCtrl
+	}
+
+	// same modifier order as tcell.EventKey.Name
+	if mod&tcell.ModShift != 0 {
+		sb.WriteString("Shift+")
+	}
+	if mod&tcell.ModAlt != 0 {
+		sb.WriteString("Alt+")
+	}
+	if mod&tcell.ModCtrl != 0 || ctrlCode {
+		sb.WriteString("Ctrl+")
+	}
+
+	switch {
+	case ctrlCode && isTypeableControl(k.Key) && k.Key != tcell.KeyBackspace:
+		// Ctrl+Enter rather than Ctrl+M, the key that was pressed. Ctrl+H
+		// stays, Backspace is the name of DEL, see canonicalNames.
+		sb.WriteString(keyName(k.Key))
+	case ctrlCode:
+		sb.WriteString(controlName(k.Key))
+	case k.Key == tcell.KeyRune && k.Rune == ' ':
+		sb.WriteString("SPC")
+	case k.Key == tcell.KeyRune:
+		sb.WriteRune(k.Rune)
+	default:
+		sb.WriteString(keyName(k.Key))
+	}
+
+	return sb.String()
+}
+
+// keyName returns the canonical name of a key that isn't a rune.
+func keyName(code tcell.Key) string {
+	if name, ok := canonicalNames[code]; ok {
+		return name
+	}
+	if name, ok := tcell.KeyNames[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("Key[%d]", code)
+}
+
+// Validate checks whether a terminal can actually send the key through tcell.
+// Keys like "Ctrl+9" parse fine but never arrive as an event, so a binding on
+// them is dead. If the key is unreachable, the returned error explains why
+// and suggestion names the key the user most likely meant, if there is one.
+func (k Key) Validate() (suggestion string, err error) {
+	ctrl := k.Mod&tcell.ModCtrl != 0
+	shift := k.Mod&tcell.ModShift != 0
+
+	switch {
+	case ctrl && k.Key == tcell.KeyRune:
+		if code, ok := ctrlDigits[k.Rune]; ok {
+			suggestion = Key{Mod: k.Mod &^ tcell.ModCtrl, Key: code}.normalize().String()
+		} else {
+			suggestion = Key{Mod: k.Mod&^tcell.ModCtrl | tcell.ModAlt, Key: tcell.KeyRune, Rune: k.Rune}.String()
+		}
+		return suggestion, fmt.Errorf("terminals cannot send Ctrl together with '%c'", k.Rune)
+	case ctrl && isTypeableControl(k.Key):
+		plain := Key{Mod: k.Mod &^ tcell.ModCtrl, Key: k.Key}.normalize()
+		return plain.String(), fmt.Errorf("%s is indistinguishable from %s", k, plain)
+	case shift && k.Key == tcell.KeyRune:
+		return "", fmt.Errorf("terminals report shifted symbols without Shift")
+	case shift && ctrl && isControlCode(k.Key):
+		return Key{Mod: k.Mod &^ tcell.ModShift, Key: k.Key}.String(), fmt.Errorf("terminals don't report Shift together with Ctrl+%s", controlName(k.Key))
+	}
+	return "", nil
+}
+
+// ctrlDigits are the control codes that terminals send for Ctrl+digit,
+// following the traditional VT220 keyboard layout.
+var ctrlDigits = map[rune]tcell.Key{
+	'2': tcell.KeyCtrlSpace,
+	'3': tcell.KeyEsc,
+	'4': tcell.KeyCtrlBackslash,
+	'5': tcell.KeyCtrlRightSq,
+	'6': tcell.KeyCtrlCarat,
+	'7': tcell.KeyCtrlUnderscore,
+	'8': tcell.KeyBackspace2,
+}
+
+// ParseKeyRange parses a range of printable keys like "[a-z]" or "[0-9]"
+// into its keys.
+func ParseKeyRange(s string) ([]Key, error) {
+	runes := []rune(s)
+	if len(runes) != 5 || runes[0] != '[' || runes[2] != '-' || runes[4] != ']' {
+		return nil, fmt.Errorf("'%s' is not a key range like '[a-z]'", s)
+	}
+	first, last := runes[1], runes[3]
+	if first > last || !unicode.IsPrint(first) || !unicode.IsPrint(last) || first == ' ' {
+		return nil, fmt.Errorf("invalid key range '%s'", s)
+	}
+
+	keys := make([]Key, 0, last-first+1)
+	for r := first; r <= last; r++ {
+		keys = append(keys, Key{Key: tcell.KeyRune, Rune: r})
+	}
+	return keys, nil
+}
+
+// SuggestKey guesses the intended spelling of a key specification that
+// ParseKey rejected, e.g. "Ctrl+Space" for "CTRL@" or "Enter" for "entr".
+// Key sequences are fixed key by key, e.g. "SPC b s" for "SPCE b s".
+// It returns the canonical name of the guess, or "" if nothing is close.
+func SuggestKey(s string) string {
+	if fields := strings.Fields(s); len(fields) > 1 {
+		// a key sequence, fix it key by key
+		for i, field := range fields {
+			if key, err := ParseKey(field); err == nil {
+				fields[i] = key.String()
+			} else if fields[i] = SuggestKey(field); fields[i] == "" {
+				return ""
+			}
+		}
+		return strings.Join(fields, " ")
+	}
+
+	spec := strings.TrimSpace(s)
+	if len(spec) > 2 && strings.HasPrefix(spec, "<") && strings.HasSuffix(spec, ">") {
+		spec = spec[1 : len(spec)-1]
+	}
+
+	// keep the modifiers that did parse and only guess the rest
+	prefix := spec
+	for {
+		_, rest, ok := cutModifier(spec)
+		if !ok {
+			break
+		}
+		spec = rest
+	}
+	prefix = prefix[:len(prefix)-len(spec)]
+
+	for _, guess := range suggestBaseKey(spec) {
+		key, err := ParseKey(prefix + guess)
+		if err != nil {
+			continue
+		}
+		if _, err := key.Validate(); err == nil {
+			return key.String()
+		}
+	}
+	return ""
+}
+
+// SuggestName returns the name of names that is closest to name, if it
+// looks like a typo of it, or "".
+func SuggestName(name string, names []string) string {
+	best, bestDistance := "", 3
+	for _, candidate := range names {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(candidate)); d < bestDistance || (d == bestDistance && candidate < best) {
+			best, bestDistance = candidate, d
+		}
+	}
+	if bestDistance >= len(name) {
+		return ""
+	}
+	return best
+}
+
+// suggestBaseKey returns candidate spellings for an unknown key name without
+// modifiers, most likely first.
+func suggestBaseKey(spec string) []string {
+	var guesses []string
+	lower := strings.ToLower(spec)
+
+	// a modifier without separator, like "CTRL@"
+	for _, word := range []string{"control", "ctrl", "shift", "meta", "alt"} {
+		if strings.HasPrefix(lower, word) && len(spec) > len(word) {
+			guesses = append(guesses, spec[:len(word)]+"+"+spec[len(word):])
+		}
+	}
+
+	// a misspelled key name, like "entr"
+	best, bestDistance := "", 3
+	for name := range keyNames {
+		if d := editDistance(lower, name); d < bestDistance || (d == bestDistance && name < best) {
+			best, bestDistance = name, d
+		}
+	}
+	for name := range spaceNames {
+		if d := editDistance(lower, name); d < bestDistance || (d == bestDistance && name < best) {
+			best, bestDistance = name, d
+		}
+	}
+	if best != "" && bestDistance < len(lower) {
+		guesses = append(guesses, best)
+	}
+
+	// stray punctuation around a letter, like "d?" or "@Q"
+	stripped := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, spec)
+	if stripped != "" && stripped != spec {
+		guesses = append(guesses, stripped)
+	}
+
+	return guesses
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,203 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKey_Spellings(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected string
+	}{
+		{"CTRL-C", "Ctrl+C"},
+		{"Ctrl+c", "Ctrl+C"},
+		{"C-c", "Ctrl+C"},
+		{"<C-c>", "Ctrl+C"},
+		{"control-C", "Ctrl+C"},
+		{"SPC", "SPC"},
+		{"space", "SPC"},
+		{" ", "SPC"},
+		{"enter", "Enter"},
+		{"RET", "Enter"},
+		{"ESC", "ESC"},
+		{"escape", "ESC"},
+		{"Esc", "ESC"},
+		{"a", "a"},
+		{"A", "A"},
+		{"S-a", "A"},
+		{"/", "/"},
+		{"-", "-"},
+		{"+", "+"},
+		{"Ctrl++", "Ctrl++"},
+		{"M-x", "Alt+x"},
+		{"Alt+X", "Alt+X"},
+		{"meta-x", "Alt+x"},
+		{"C-M-x", "Alt+Ctrl+X"},
+		{"Shift+Tab", "Backtab"},
+		{"Shift+Up", "Shift+Up"},
+		{"f5", "F5"},
+		{"PageUp", "PgUp"},
+		{"bs", "Backspace"},
+		{"Ctrl+h", "Ctrl+H"},
+		{"Ctrl+Space", "Ctrl+Space"},
+		{"Ctrl+]", "Ctrl+]"},
+		{"Ctrl+[", "Ctrl+ESC"},
+		{"C-m", "Ctrl+Enter"},
+		{"Ctrl+Tab", "Ctrl+Tab"},
+		{"Ctrl+Backspace", "Ctrl+Backspace"},
+		{"Ctrl-9", "Ctrl+9"},
+	}
+
+	for _, tt := range tests {
+		key, err := ParseKey(tt.spec)
+		if assert.NoError(t, err, "ParseKey(%q) should succeed", tt.spec) {
+			assert.Equal(t, tt.expected, key.String(), "canonical form of %q", tt.spec)
+		}
+	}
+}
+
+func TestParseKey_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "  ", "d?", "@Q", "CTRL@", "Ctrl-", "key with spaces", "Hyper+x"} {
+		_, err := ParseKey(spec)
+		assert.Error(t, err, "ParseKey(%q) should fail", spec)
+	}
+}
+
+func TestParseKey_RoundTrip(t *testing.T) {
+	for _, spec := range []string{"Ctrl+C", "ESC", "SPC", "Enter", "Alt+x", "G", "Shift+Up", "Ctrl+Space", "Ctrl+[", "Ctrl+Enter", "Ctrl+H", "F12", "Backspace"} {
+		key, err := ParseKey(spec)
+		if assert.NoError(t, err) {
+			again, err := ParseKey(key.String())
+			assert.NoError(t, err)
+			assert.Equal(t, key, again, "%q should survive a round trip", spec)
+		}
+	}
+}
+
+func TestKeyFromEvent_MatchesParsedKey(t *testing.T) {
+	tests := []struct {
+		spec string
+		ev   *tcell.EventKey
+	}{
+		{"Ctrl-C", tcell.NewEventKey(tcell.KeyCtrlC, 0, tcell.ModCtrl)},
+		{"Ctrl-C", tcell.NewEventKey(tcell.KeyCtrlC, 0, tcell.ModNone)},
+		{"Ctrl-C", tcell.NewEventKey(tcell.KeyRune, 3, tcell.ModNone)},
+		{"ESC", tcell.NewEventKey(tcell.KeyEsc, 0, tcell.ModNone)},
+		{"enter", tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone)},
+		{"Tab", tcell.NewEventKey(tcell.KeyRune, '\t', tcell.ModNone)},
+		{"SPC", tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModNone)},
+		{"g", tcell.NewEventKey(tcell.KeyRune, 'g', tcell.ModNone)},
+		{"G", tcell.NewEventKey(tcell.KeyRune, 'G', tcell.ModShift)},
+		{"M-x", tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModAlt)},
+		{"Backspace", tcell.NewEventKey(tcell.KeyBackspace, 0, tcell.ModNone)},
+		{"Backspace", tcell.NewEventKey(tcell.KeyBackspace2, 0, tcell.ModNone)},
+		{"Shift+Tab", tcell.NewEventKey(tcell.KeyBacktab, 0, tcell.ModNone)},
+	}
+
+	for _, tt := range tests {
+		parsed, err := ParseKey(tt.spec)
+		if assert.NoError(t, err) {
+			assert.Equal(t, parsed, KeyFromEvent(tt.ev), "event %s should match %q", tt.ev.Name(), tt.spec)
+		}
+	}
+}
+
+func TestKeyValidate(t *testing.T) {
+	tests := []struct {
+		spec       string
+		valid      bool
+		suggestion string
+	}{
+		{"Ctrl+C", true, ""},
+		{"ESC", true, ""},
+		{"Alt+9", true, ""},
+		{"Shift+Up", true, ""},
+		{"Ctrl-9", false, "Alt+9"},
+		{"Ctrl+2", false, "Ctrl+Space"},
+		{"Ctrl+3", false, "ESC"},
+		{"Ctrl+H", false, "Backspace"},
+		{"Ctrl+I", false, "Tab"},
+		{"Ctrl+M", false, "Enter"},
+		{"Shift+1", false, ""},
+		{"C-S-a", false, "Ctrl+A"},
+	}
+
+	for _, tt := range tests {
+		key, err := ParseKey(tt.spec)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		suggestion, err := key.Validate()
+		if tt.valid {
+			assert.NoError(t, err, "%q should be reachable", tt.spec)
+		} else {
+			assert.Error(t, err, "%q should be unreachable", tt.spec)
+		}
+		assert.Equal(t, tt.suggestion, suggestion, "suggestion for %q", tt.spec)
+	}
+}
+
+func TestKeyValidate_Message(t *testing.T) {
+	tests := map[string]string{
+		"Ctrl+Enter": "Ctrl+Enter is indistinguishable from Enter",
+		"Ctrl+Tab":   "Ctrl+Tab is indistinguishable from Tab",
+		"Ctrl+ESC":   "Ctrl+ESC is indistinguishable from ESC",
+		"Ctrl+H":     "Ctrl+H is indistinguishable from Backspace",
+	}
+
+	for spec, message := range tests {
+		key, err := ParseKey(spec)
+		if assert.NoError(t, err) {
+			_, err = key.Validate()
+			assert.EqualError(t, err, message, spec)
+		}
+	}
+}
+
+func TestSuggestKey(t *testing.T) {
+	tests := map[string]string{
+		"d?":        "d",
+		"@Q":        "Q",
+		"CTRL@":     "Ctrl+Space",
+		"entr":      "Enter",
+		"Escpe":     "ESC",
+		"spce":      "SPC",
+		"Ctrl+spce": "Ctrl+Space",
+		"key1":      "",
+		"xy":        "",
+	}
+
+	for spec, expected := range tests {
+		assert.Equal(t, expected, SuggestKey(spec), "suggestion for %q", spec)
+	}
+}
+
+func TestSuggestName(t *testing.T) {
+	fields := []string{"bindings", "context_add", "context_override", "settings"}
+	assert.Equal(t, "context_add", SuggestName("contxt_add", fields))
+	assert.Equal(t, "bindings", SuggestName("Bindngs", fields))
+	assert.Equal(t, "", SuggestName("TrackList", fields))
+	assert.Equal(t, "", SuggestName("ab", []string{"x"}), "Names should not be replaced entirely")
+}
+
+func TestParseKeyRange(t *testing.T) {
+	keys, err := ParseKeyRange("[a-z]")
+	if assert.NoError(t, err) {
+		assert.Len(t, keys, 26)
+		parsed, _ := ParseKey("q")
+		assert.Contains(t, keys, parsed, "Range keys should match parsed keys")
+	}
+
+	keys, err = ParseKeyRange("[A-C]")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "A B C", SequenceString(keys))
+	}
+
+	for _, spec := range []string{"a", "[a-]", "[z-a]", "[ -z]", "a-z"} {
+		_, err := ParseKeyRange(spec)
+		assert.Error(t, err, "ParseKeyRange(%q) should fail", spec)
+	}
+}
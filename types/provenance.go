@@ -0,0 +1,135 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Inheritance is how a context got a binding.
+type Inheritance int
+
+const (
+	// InheritOwn means the binding is defined in the context itself.
+	InheritOwn Inheritance = iota
+	// InheritDefault means it was inherited implicitly from Default.
+	InheritDefault
+	// InheritAdd means it came from a context in context_add.
+	InheritAdd
+	// InheritOverride means it came from a context in context_override.
+	InheritOverride
+	// InheritParent means it was inherited implicitly from the parent of a
+	// sub-context, e.g. by Playlist.TrackList from Playlist.
+	InheritParent
+)
+
+func (i Inheritance) String() string {
+	switch i {
+	case InheritOwn:
+		return "own"
+	case InheritDefault:
+		return "default"
+	case InheritAdd:
+		return "add"
+	case InheritOverride:
+		return "override"
+	case InheritParent:
+		return "parent"
+	}
+	return fmt.Sprintf("Inheritance(%d)", int(i))
+}
+
+// Step is one link of a binding's provenance: Context got the binding in the
+// way Via says, from the context of the next step.
+type Step struct {
+	Context string
+	Via     Inheritance
+}
+
+// Origin returns the context the binding is defined in, or "" if it wasn't
+// resolved by LoadConfig.
+func (b Binding) Origin() string {
+	if len(b.Provenance) == 0 {
+		return ""
+	}
+	return b.Provenance[len(b.Provenance)-1].Context
+}
+
+// ProvenanceString describes the provenance of the binding, e.g.
+// "Queue <-add- ListPreset <-default- Default".
+func (b Binding) ProvenanceString() string {
+	var sb strings.Builder
+	for i, step := range b.Provenance {
+		if i > 0 {
+			fmt.Fprintf(&sb, " <-%s- ", b.Provenance[i-1].Via)
+		}
+		sb.WriteString(step.Context)
+	}
+	return sb.String()
+}
+
+// Inherited returns a copy of the binding as seen by a context that got it
+// in the way via says, with a step for that context in front of the
+// provenance of the binding and of the bindings it shadowed.
+func (b Binding) Inherited(context string, via Inheritance) Binding {
+	inherited := b
+	inherited.Provenance = append([]Step{{Context: context, Via: via}}, b.Provenance...)
+	if b.Shadowed != nil {
+		inherited.Shadowed = make([]Binding, len(b.Shadowed))
+		for i, shadowed := range b.Shadowed {
+			inherited.Shadowed[i] = shadowed.Inherited(context, via)
+		}
+	}
+	return inherited
+}
+
+// Shadow returns the binding with other and everything other shadowed added
+// to the bindings it took precedence over. A context defines a key only
+// once, so bindings that arrived on another way from a context that is
+// already listed are left out.
+func (b Binding) Shadow(other Binding) Binding {
+	candidates := append([]Binding{other}, other.Shadowed...)
+	candidates[0].Shadowed = nil
+
+	shadowed := append([]Binding(nil), b.Shadowed...)
+	for _, candidate := range candidates {
+		if !containsOrigin(shadowed, candidate.Origin()) && (candidate.Origin() == "" || candidate.Origin() != b.Origin()) {
+			shadowed = append(shadowed, candidate)
+		}
+	}
+	b.Shadowed = shadowed
+	return b
+}
+
+func containsOrigin(bindings []Binding, origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, binding := range bindings {
+		if binding.Origin() == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// Explain returns how a key is bound in a context of a loaded config: the
+// binding that is used first, then the bindings of other contexts it
+// shadowed. Each one tells where it was defined and how it was inherited,
+// see Binding.Provenance. The result is empty if the key isn't bound.
+func (c Config) Explain(contextName, keyName string) ([]Binding, error) {
+	context, ok := c[contextName]
+	if !ok {
+		return nil, fmt.Errorf("context '%s' not found", contextName)
+	}
+	if keys, err := ParseKeySequence(keyName); err == nil {
+		keyName = SequenceString(keys)
+	}
+
+	binding, ok := context.Bindings[keyName]
+	if !ok {
+		return nil, nil
+	}
+	chain := append([]Binding{binding}, binding.Shadowed...)
+	chain[0].Shadowed = nil
+	return chain, nil
+}
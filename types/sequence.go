@@ -0,0 +1,429 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSequenceTimeout is how long a SequenceMatcher waits for the next key
+// after an ambiguous prefix, e.g. "g" when both "g" and "g g" are bound.
+const DefaultSequenceTimeout = time.Second
+
+// ParseKeySequence parses a whitespace separated list of keys like "g g" or
+// "SPC b s". A single key is a sequence of length one.
+func ParseKeySequence(s string) ([]Key, error) {
+	fields := strings.Fields(s)
+	if len(fields) <= 1 {
+		key, err := ParseKey(s)
+		if err != nil {
+			return nil, err
+		}
+		return []Key{key}, nil
+	}
+
+	keys := make([]Key, 0, len(fields))
+	for _, field := range fields {
+		key, err := ParseKey(field)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// SequenceString returns the canonical name of a key sequence, which is the
+// canonical names of its keys separated by single spaces.
+func SequenceString(keys []Key) string {
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key.String()
+	}
+	return strings.Join(names, " ")
+}
+
+// CanonicalKeyName returns the canonical name of a key or key sequence as
+// written in a config, e.g. "Ctrl+C" for "C-c", or keyName itself if it
+// doesn't parse.
+func CanonicalKeyName(keyName string) string {
+	if keys, err := ParseKeySequence(keyName); err == nil {
+		return SequenceString(keys)
+	}
+	return keyName
+}
+
+// SequenceState is the outcome of feeding a key to a SequenceMatcher.
+type SequenceState int
+
+const (
+	// SequenceAborted means the keys typed so far are not bound to anything.
+	// The keys are discarded.
+	SequenceAborted SequenceState = iota
+	// SequencePending means the keys typed so far are the prefix of at
+	// least one longer binding, so the matcher waits for more keys.
+	SequencePending
+	// SequenceMatched means the keys typed so far form a complete binding.
+	SequenceMatched
+)
+
+func (s SequenceState) String() string {
+	switch s {
+	case SequenceAborted:
+		return "aborted"
+	case SequencePending:
+		return "pending"
+	case SequenceMatched:
+		return "matched"
+	}
+	return fmt.Sprintf("SequenceState(%d)", int(s))
+}
+
+// keyTrie is a prefix tree of the key sequences bound in one context.
+type keyTrie struct {
+	children map[Key]*keyTrie
+	binding  Binding
+	bound    bool
+}
+
+// newKeyTrie builds the trie for a context's bindings. Bindings whose key
+// names don't parse are left out, they can't be typed anyway.
+func newKeyTrie(bindings map[string]Binding) *keyTrie {
+	root := &keyTrie{}
+	for keyName, binding := range bindings {
+		keys, err := ParseKeySequence(keyName)
+		if err != nil {
+			continue
+		}
+
+		node := root
+		for _, key := range keys {
+			if node.children == nil {
+				node.children = make(map[Key]*keyTrie)
+			}
+			child, ok := node.children[key]
+			if !ok {
+				child = &keyTrie{}
+				node.children[key] = child
+			}
+			node = child
+		}
+		node.binding = binding
+		node.bound = true
+	}
+	return root
+}
+
+// walk returns the node that keys lead to, or nil if no binding starts with
+// them.
+func (t *keyTrie) walk(keys []Key) *keyTrie {
+	node := t
+	for _, key := range keys {
+		if node = node.children[key]; node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+// sameContexts reports whether two context stacks are the same. A pending
+// sequence of Feed has no stack, it is never the same as one of FeedStack.
+func sameContexts(a, b []string) bool {
+	if a == nil || b == nil || len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// SequenceMatcher matches key events against multi-key bindings like "g g".
+// It keeps the keys typed so far and walks a prefix tree of the bindings of
+// the context the keys are typed in.
+//
+// If a prefix is bound on its own and also starts a longer binding, the
+// matcher waits Timeout for the next key. When the time is up the prefix's
+// binding is matched and reported to OnTimeout. Prefixes that aren't bound
+// on their own wait for the next key indefinitely, like a leader key.
+type SequenceMatcher struct {
+	// Timeout for ambiguous prefixes. Zero disables the timer, the app has
+	// to call Flush itself then.
+	Timeout time.Duration
+	// OnTimeout is called with the matched event when an ambiguous prefix
+	// times out. It runs on its own goroutine.
+	OnTimeout func(e *Event)
+
+	mu             sync.Mutex
+	config         *Config
+	tries          map[string]*keyTrie
+	pending        []Key
+	pendingContext string
+	pendingStack   []string // the stack of FeedStack, nil for Feed
+	pendingState   map[string]interface{}
+	timer          *time.Timer
+	generation     int
+}
+
+// NewSequenceMatcher creates a matcher for the bindings in config.
+// The timeout is taken from the "sequence_timeout" setting of the Global
+// context (e.g. "500ms") and defaults to DefaultSequenceTimeout.
+func NewSequenceMatcher(config *Config) *SequenceMatcher {
+	m := &SequenceMatcher{
+		Timeout: DefaultSequenceTimeout,
+		config:  config,
+		tries:   make(map[string]*keyTrie),
+	}
+
+	if config != nil {
+		if setting, ok := (*config)["Global"].Settings[SettingSequenceTimeout].(string); ok {
+			if timeout, err := time.ParseDuration(setting); err == nil {
+				m.Timeout = timeout
+			}
+		}
+	}
+
+	return m
+}
+
+// Feed adds the key of e to the sequence typed so far in the given context
+// and looks up the result. On SequenceMatched, e.Command and e.IsBound are
+// set like LookupCommand does, including the "when" conditions. e.Sequence
+// holds the keys typed so far.
+//
+// Switching to another context discards a pending sequence.
+func (m *SequenceMatcher) Feed(e *Event, contextKey string) (SequenceState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e.unbind()
+
+	trie, err := m.trie(contextKey)
+	if err != nil {
+		m.stopTimer()
+		m.reset()
+		e.Sequence = []Key{e.Key}
+		return SequenceAborted, err
+	}
+
+	if contextKey != m.pendingContext || m.pendingStack != nil {
+		m.reset()
+	}
+	m.stopTimer()
+
+	keys := make([]Key, 0, len(m.pending)+1)
+	keys = append(append(keys, m.pending...), e.Key)
+	e.Sequence = keys
+
+	node := trie.walk(keys)
+	if node == nil {
+		m.reset()
+		return SequenceAborted, nil
+	}
+
+	if len(node.children) == 0 {
+		m.reset()
+		binding, found, err := node.binding.Select(e.State)
+		if err != nil || !found || binding.Unbind {
+			return SequenceAborted, err
+		}
+		return SequenceMatched, e.bind(binding, contextKey)
+	}
+
+	m.pending = keys
+	m.pendingContext = contextKey
+	m.pendingState = e.State
+	if node.bound && m.Timeout > 0 {
+		m.startTimer()
+	}
+	return SequencePending, nil
+}
+
+// FeedStack is like Feed, but looks up the keys typed so far in all contexts
+// on the stack with the rules of Event.LookupStack. The topmost context that
+// binds the keys, or a longer sequence that starts with them, wins, so a
+// sequence bound in Global completes while a child context is focused. Keys
+// that aren't bound in an opaque context only fall through if the first key
+// of the sequence is in its fallthrough_keys setting. On SequenceMatched,
+// e.Context is the context that binds the sequence.
+//
+// Changing the stack discards a pending sequence.
+func (m *SequenceMatcher) FeedStack(e *Event, stack *ContextStack) (SequenceState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e.unbind()
+
+	if m.config == nil {
+		m.stopTimer()
+		m.reset()
+		e.Sequence = []Key{e.Key}
+		return SequenceAborted, fmt.Errorf("tviewcommand.types.SequenceMatcher.config is nil")
+	}
+
+	contexts := stack.Contexts()
+	if !sameContexts(contexts, m.pendingStack) {
+		m.reset()
+	}
+	m.stopTimer()
+
+	keys := make([]Key, 0, len(m.pending)+1)
+	keys = append(append(keys, m.pending...), e.Key)
+	e.Sequence = keys
+	m.reset()
+
+	for layer := len(contexts) - 1; layer >= 0; layer-- {
+		context, ok := (*m.config)[contexts[layer]]
+		if !ok {
+			continue
+		}
+		trie, err := m.trie(contexts[layer])
+		if err != nil {
+			return SequenceAborted, err
+		}
+
+		if node := trie.walk(keys); node != nil {
+			if len(node.children) > 0 {
+				m.pending = keys
+				m.pendingContext = contexts[layer]
+				m.pendingStack = contexts
+				m.pendingState = e.State
+				if node.bound && m.Timeout > 0 {
+					m.startTimer()
+				}
+				return SequencePending, nil
+			}
+
+			binding, found, err := node.binding.Select(e.State)
+			if err != nil {
+				return SequenceAborted, err
+			}
+			if found && binding.Unbind {
+				// explicitly unbound, the contexts below don't get the keys either
+				break
+			}
+			if found {
+				return SequenceMatched, e.bind(binding, contexts[layer])
+			}
+		}
+
+		if !context.FallsThrough(keys[0].String()) {
+			break
+		}
+	}
+	return SequenceAborted, nil
+}
+
+// Pending returns the keys typed so far that wait for completion.
+func (m *SequenceMatcher) Pending() []Key {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Key(nil), m.pending...)
+}
+
+// Flush ends a pending sequence right away. If the keys typed so far are
+// bound on their own, the returned event carries their binding and the state
+// is SequenceMatched, otherwise it is SequenceAborted. It returns nil if no
+// sequence is pending.
+func (m *SequenceMatcher) Flush() (*Event, SequenceState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stopTimer()
+	return m.flush()
+}
+
+// Reset discards a pending sequence.
+func (m *SequenceMatcher) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stopTimer()
+	m.reset()
+}
+
+func (m *SequenceMatcher) flush() (*Event, SequenceState) {
+	if len(m.pending) == 0 {
+		return nil, SequenceAborted
+	}
+
+	e := &Event{
+		Key:      m.pending[len(m.pending)-1],
+		KeyName:  m.pending[len(m.pending)-1].String(),
+		Sequence: m.pending,
+		Config:   m.config,
+		State:    m.pendingState,
+	}
+	contextKey := m.pendingContext
+	node := m.tries[contextKey]
+	for _, key := range m.pending {
+		node = node.children[key]
+	}
+	m.reset()
+
+	if !node.bound {
+		return e, SequenceAborted
+	}
+	binding, found, err := node.binding.Select(e.State)
+	if err != nil || !found || binding.Unbind {
+		return e, SequenceAborted
+	}
+	// a syntax error is left for the app to find when it runs the command
+	_ = e.bind(binding, contextKey)
+	return e, SequenceMatched
+}
+
+func (m *SequenceMatcher) trie(contextKey string) (*keyTrie, error) {
+	if m.config == nil {
+		return nil, fmt.Errorf("tviewcommand.types.SequenceMatcher.config is nil")
+	}
+	if trie, ok := m.tries[contextKey]; ok {
+		return trie, nil
+	}
+
+	context, ok := (*m.config)[contextKey]
+	if !ok {
+		return nil, fmt.Errorf("Lookup failed: Context '%s' not found.", contextKey)
+	}
+	trie := newKeyTrie(context.Bindings)
+	m.tries[contextKey] = trie
+	return trie, nil
+}
+
+func (m *SequenceMatcher) reset() {
+	m.pending = nil
+	m.pendingContext = ""
+	m.pendingStack = nil
+	m.pendingState = nil
+}
+
+func (m *SequenceMatcher) startTimer() {
+	generation := m.generation
+	m.timer = time.AfterFunc(m.Timeout, func() {
+		m.mu.Lock()
+		if generation != m.generation {
+			// a key arrived in the meantime
+			m.mu.Unlock()
+			return
+		}
+		e, state := m.flush()
+		onTimeout := m.OnTimeout
+		m.mu.Unlock()
+
+		if state == SequenceMatched && onTimeout != nil {
+			onTimeout(e)
+		}
+	})
+}
+
+func (m *SequenceMatcher) stopTimer() {
+	m.generation++
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer = nil
+	}
+}
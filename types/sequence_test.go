@@ -0,0 +1,300 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runeEvent(r rune, config *Config) *Event {
+	return FromEventKey(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone), config)
+}
+
+func sequenceConfig() *Config {
+	return &Config{
+		"ListPreset": Context{
+			Bindings: map[string]Binding{
+				"g g": {Command: "goToTop"},
+				"G":   {Command: "goToBottom"},
+				"g":   {Command: "goToFirstVisible"},
+			},
+		},
+		"Default": Context{
+			Bindings: map[string]Binding{
+				"SPC b s": {Command: "badges.show"},
+				"SPC b h": {Command: "badges.hide"},
+				"d":       {Command: "deleteTrack"},
+			},
+		},
+	}
+}
+
+func TestParseKeySequence(t *testing.T) {
+	keys, err := ParseKeySequence("SPC  b s")
+	require.NoError(t, err)
+	assert.Equal(t, "SPC b s", SequenceString(keys))
+
+	keys, err = ParseKeySequence("C-x C-s")
+	require.NoError(t, err)
+	assert.Equal(t, "Ctrl+X Ctrl+S", SequenceString(keys))
+
+	keys, err = ParseKeySequence(" ")
+	require.NoError(t, err)
+	assert.Equal(t, "SPC", SequenceString(keys))
+
+	_, err = ParseKeySequence("g foo")
+	assert.Error(t, err)
+}
+
+func TestCanonicalKeyName(t *testing.T) {
+	assert.Equal(t, "Ctrl+C", CanonicalKeyName("C-c"))
+	assert.Equal(t, "SPC b s", CanonicalKeyName("SPC  b s"))
+	assert.Equal(t, "g foo", CanonicalKeyName("g foo"), "Names that don't parse should be kept")
+}
+
+func TestSequenceMatcher_LeaderSequence(t *testing.T) {
+	config := sequenceConfig()
+	m := NewSequenceMatcher(config)
+
+	state, err := m.Feed(runeEvent(' ', config), "Default")
+	require.NoError(t, err)
+	assert.Equal(t, SequencePending, state)
+
+	state, err = m.Feed(runeEvent('b', config), "Default")
+	require.NoError(t, err)
+	assert.Equal(t, SequencePending, state)
+	assert.Equal(t, "SPC b", SequenceString(m.Pending()))
+
+	e := runeEvent('s', config)
+	state, err = m.Feed(e, "Default")
+	require.NoError(t, err)
+	assert.Equal(t, SequenceMatched, state)
+	assert.True(t, e.IsBound)
+	assert.Equal(t, "badges.show", e.Command)
+	assert.Equal(t, "SPC b s", SequenceString(e.Sequence))
+	assert.Empty(t, m.Pending())
+}
+
+func TestSequenceMatcher_SingleKey(t *testing.T) {
+	config := sequenceConfig()
+	m := NewSequenceMatcher(config)
+
+	e := runeEvent('d', config)
+	state, err := m.Feed(e, "Default")
+	require.NoError(t, err)
+	assert.Equal(t, SequenceMatched, state)
+	assert.Equal(t, "deleteTrack", e.Command)
+}
+
+func TestSequenceMatcher_Aborted(t *testing.T) {
+	config := sequenceConfig()
+	m := NewSequenceMatcher(config)
+
+	_, err := m.Feed(runeEvent(' ', config), "Default")
+	require.NoError(t, err)
+
+	e := runeEvent('x', config)
+	state, err := m.Feed(e, "Default")
+	require.NoError(t, err)
+	assert.Equal(t, SequenceAborted, state)
+	assert.False(t, e.IsBound)
+	assert.Equal(t, "SPC x", SequenceString(e.Sequence))
+	assert.Empty(t, m.Pending(), "Aborted keys should be discarded")
+
+	// an unbound single key is aborted right away
+	state, err = m.Feed(runeEvent('z', config), "Default")
+	require.NoError(t, err)
+	assert.Equal(t, SequenceAborted, state)
+}
+
+func TestSequenceMatcher_AmbiguousPrefix(t *testing.T) {
+	config := sequenceConfig()
+	m := NewSequenceMatcher(config)
+	m.Timeout = 0 // flush by hand
+
+	state, err := m.Feed(runeEvent('g', config), "ListPreset")
+	require.NoError(t, err)
+	assert.Equal(t, SequencePending, state, "'g' could still become 'g g'")
+
+	e := runeEvent('g', config)
+	state, err = m.Feed(e, "ListPreset")
+	require.NoError(t, err)
+	assert.Equal(t, SequenceMatched, state)
+	assert.Equal(t, "goToTop", e.Command)
+
+	_, err = m.Feed(runeEvent('g', config), "ListPreset")
+	require.NoError(t, err)
+	flushed, state := m.Flush()
+	assert.Equal(t, SequenceMatched, state)
+	require.NotNil(t, flushed)
+	assert.Equal(t, "goToFirstVisible", flushed.Command)
+
+	flushed, state = m.Flush()
+	assert.Nil(t, flushed, "Nothing should be pending after a flush")
+	assert.Equal(t, SequenceAborted, state)
+}
+
+func TestSequenceMatcher_Timeout(t *testing.T) {
+	config := sequenceConfig()
+	m := NewSequenceMatcher(config)
+	m.Timeout = 10 * time.Millisecond
+
+	timedOut := make(chan *Event, 1)
+	m.OnTimeout = func(e *Event) {
+		timedOut <- e
+	}
+
+	_, err := m.Feed(runeEvent('g', config), "ListPreset")
+	require.NoError(t, err)
+
+	select {
+	case e := <-timedOut:
+		assert.Equal(t, "goToFirstVisible", e.Command)
+		assert.True(t, e.IsBound)
+	case <-time.After(time.Second):
+		t.Fatal("Ambiguous prefix should have timed out")
+	}
+	assert.Empty(t, m.Pending())
+}
+
+func TestSequenceMatcher_ContextSwitchResets(t *testing.T) {
+	config := sequenceConfig()
+	m := NewSequenceMatcher(config)
+
+	_, err := m.Feed(runeEvent(' ', config), "Default")
+	require.NoError(t, err)
+
+	e := runeEvent('G', config)
+	state, err := m.Feed(e, "ListPreset")
+	require.NoError(t, err)
+	assert.Equal(t, SequenceMatched, state)
+	assert.Equal(t, "goToBottom", e.Command)
+}
+
+func TestSequenceMatcher_TimeoutSetting(t *testing.T) {
+	config := &Config{
+		"Global": Context{Settings: map[string]interface{}{SettingSequenceTimeout: "250ms"}},
+	}
+
+	m := NewSequenceMatcher(config)
+	assert.Equal(t, 250*time.Millisecond, m.Timeout)
+	assert.Equal(t, DefaultSequenceTimeout, NewSequenceMatcher(&Config{}).Timeout)
+}
+
+func TestSequenceMatcher_ContextNotFound(t *testing.T) {
+	config := sequenceConfig()
+	m := NewSequenceMatcher(config)
+
+	state, err := m.Feed(runeEvent('g', config), "NonExistentContext")
+	assert.EqualError(t, err, "Lookup failed: Context 'NonExistentContext' not found.")
+	assert.Equal(t, SequenceAborted, state)
+}
+
+func TestSequenceMatcher_FeedStack(t *testing.T) {
+	config := &Config{
+		"Global": Context{
+			Bindings: map[string]Binding{
+				"SPC b s": {Command: "badges.show"},
+				"g g":     {Command: "goToTop"},
+				"q":       {Command: "quit"},
+			},
+		},
+		"TrackList": Context{
+			Bindings: map[string]Binding{
+				"SPC p": {Command: "play"},
+				"d":     {Command: "deleteTrack"},
+				"g":     {Command: "goToFirstVisible"},
+			},
+		},
+		"Dialog": Context{
+			Settings: map[string]interface{}{
+				SettingOpaque:          true,
+				SettingFallthroughKeys: []interface{}{"SPC"},
+			},
+			Bindings: map[string]Binding{
+				"y": {Command: "confirm"},
+			},
+		},
+	}
+	m := NewSequenceMatcher(config)
+	m.Timeout = 0
+	stack := NewContextStack()
+	stack.Push("TrackList")
+
+	// a sequence bound in Global completes while TrackList is focused
+	for _, r := range " b" {
+		state, err := m.FeedStack(runeEvent(r, config), stack)
+		require.NoError(t, err)
+		assert.Equal(t, SequencePending, state)
+	}
+	e := runeEvent('s', config)
+	state, err := m.FeedStack(e, stack)
+	require.NoError(t, err)
+	assert.Equal(t, SequenceMatched, state)
+	assert.Equal(t, "badges.show", e.Command)
+	assert.Equal(t, "Global", e.Context)
+
+	// the leader key starts sequences of both contexts
+	_, err = m.FeedStack(runeEvent(' ', config), stack)
+	require.NoError(t, err)
+	e = runeEvent('p', config)
+	state, err = m.FeedStack(e, stack)
+	require.NoError(t, err)
+	assert.Equal(t, SequenceMatched, state)
+	assert.Equal(t, "play", e.Command)
+	assert.Equal(t, "TrackList", e.Context)
+
+	// "g" in TrackList hides "g g" in Global, like LookupStack
+	e = runeEvent('g', config)
+	state, err = m.FeedStack(e, stack)
+	require.NoError(t, err)
+	assert.Equal(t, SequenceMatched, state)
+	assert.Equal(t, "goToFirstVisible", e.Command)
+
+	// an opaque context only lets its fallthrough_keys pass
+	stack.Push("Dialog")
+	state, err = m.FeedStack(runeEvent('q', config), stack)
+	require.NoError(t, err)
+	assert.Equal(t, SequenceAborted, state)
+
+	_, err = m.FeedStack(runeEvent(' ', config), stack)
+	require.NoError(t, err)
+	assert.Equal(t, "SPC", SequenceString(m.Pending()))
+
+	// changing the stack discards the pending sequence
+	stack.Pop()
+	e = runeEvent('d', config)
+	state, err = m.FeedStack(e, stack)
+	require.NoError(t, err)
+	assert.Equal(t, SequenceMatched, state)
+	assert.Equal(t, "deleteTrack", e.Command)
+}
+
+func TestSequenceMatcher_FeedStackUnbound(t *testing.T) {
+	config := &Config{
+		"Global": Context{
+			Bindings: map[string]Binding{
+				"SPC b s": {Command: "badges.show"},
+			},
+		},
+		"Queue": Context{
+			Bindings: map[string]Binding{
+				"SPC b s": {Unbind: true},
+			},
+		},
+	}
+	m := NewSequenceMatcher(config)
+	stack := NewContextStack()
+	stack.Push("Queue")
+
+	var state SequenceState
+	var err error
+	for _, r := range " bs" {
+		state, err = m.FeedStack(runeEvent(r, config), stack)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, SequenceAborted, state, "Unbinding in Queue hides the binding of Global")
+}
@@ -57,6 +57,12 @@ func (cs *ContextStack) Current() string {
 	return "Global"
 }
 
+// Contexts returns a copy of the stack, from Global at the bottom to the
+// current context at the top.
+func (cs *ContextStack) Contexts() []string {
+	return append([]string(nil), cs.stack...)
+}
+
 // Reset clears the stack and resets to the Global context.
 func (cs *ContextStack) Reset() {
 	cs.stack = []string{"Global"}
@@ -55,6 +55,19 @@ func TestContextStack_MultiplePushes(t *testing.T) {
 	assert.Equal(t, "QueueDetails", stack.Current(), "Current context should be QueueDetails")
 }
 
+func TestContextStack_Contexts(t *testing.T) {
+	stack := NewContextStack()
+	stack.Push("QueuePage")
+	stack.Push("QueueList")
+
+	contexts := stack.Contexts()
+	assert.Equal(t, []string{"Global", "QueuePage", "QueueList"}, contexts, "Contexts should list bottom to top")
+
+	// modifying the copy must not affect the stack
+	contexts[2] = "Other"
+	assert.Equal(t, "QueueList", stack.Current())
+}
+
 func TestContextStack_PrintStack(t *testing.T) {
 	stack := NewContextStack()
 